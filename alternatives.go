@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// alternativeGroup lists the apps registered as providers of an executable
+// name. In auto mode the available provider with the highest priority owns
+// the links; a provider chosen with lav alternatives stays active for as
+// long as it is available.
+type alternativeGroup struct {
+	Providers map[string]int `json:"providers"`        // app -> priority
+	Manual    string         `json:"manual,omitempty"` // chosen provider, "" in auto mode
+}
+
+// alternativeInfo describes a provider listed by lav alternatives.
+type alternativeInfo struct {
+	app       string
+	priority  int
+	available bool // the app's current version has the executable
+}
+
+// isProvider reports whether app is a registered provider of name.
+func (r *linkRegistry) isProvider(name, app string) bool {
+	group, ok := r.Alternatives[name]
+	if !ok {
+		return false
+	}
+	_, ok = group.Providers[app]
+	return ok
+}
+
+// providedBy returns the executable names app is a registered provider of.
+func (r *linkRegistry) providedBy(app string) []string {
+	var names []string
+	for name, group := range r.Alternatives {
+		if _, ok := group.Providers[app]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// providerAvailable reports whether provider's current version has
+// executable name. For appName, which is being relinked, executables is
+// used instead of its current version.
+func providerAvailable(baseDir, provider, name, appName string, executables map[string]bool) bool {
+	if provider == appName {
+		return executables[name]
+	}
+	available, err := appExecutables(baseDir, provider, "current")
+	return err == nil && available[name]
+}
+
+// activeProvider returns the provider of name whose links are active: the
+// manually chosen provider if it is available, otherwise the available
+// provider with the highest priority (ties go to the first app by name).
+// It returns "" if no provider is available.
+func activeProvider(baseDir string, group *alternativeGroup, name, appName string, executables map[string]bool) string {
+	if group.Manual != "" && providerAvailable(baseDir, group.Manual, name, appName, executables) {
+		return group.Manual
+	}
+
+	active := ""
+	for _, app := range slices.Sorted(maps.Keys(group.Providers)) {
+		if !providerAvailable(baseDir, app, name, appName, executables) {
+			continue
+		}
+		if active == "" || group.Providers[app] > group.Providers[active] {
+			active = app
+		}
+	}
+	return active
+}
+
+// applyAlternative points the links for name in each bin directory at its
+// active provider, or removes them if no provider is available. Links owned
+// by any provider are replaced freely; others only with force.
+func applyAlternative(baseDir string, reg *linkRegistry, binDirs []string, name, appName string, executables map[string]bool, force bool) ([]string, []string, error) {
+	group := reg.Alternatives[name]
+	active := activeProvider(baseDir, group, name, appName, executables)
+
+	var changed, conflicts []string
+	for _, binDir := range binDirs {
+		link := filepath.Join(binDir, name)
+		_, ownedByProvider := group.Providers[reg.owner(baseDir, link)]
+
+		if active == "" {
+			info, err := os.Lstat(link)
+			if err != nil || info.Mode()&os.ModeSymlink == 0 || !ownedByProvider {
+				continue
+			}
+			if err := os.Remove(link); err != nil {
+				return changed, conflicts, fmt.Errorf("failed to remove bin symlink: %w", err)
+			}
+			delete(reg.Links, link)
+			changed = append(changed, link)
+			continue
+		}
+
+		if conflict := linkConflict(baseDir, reg, link, active, force || ownedByProvider); conflict != "" {
+			conflicts = append(conflicts, conflict)
+			continue
+		}
+		if err := os.MkdirAll(binDir, 0755); err != nil {
+			return changed, conflicts, fmt.Errorf("failed to create %s: %w", binDir, err)
+		}
+		updated, err := pointLink(baseDir, reg, binDir, active, name)
+		if err != nil {
+			return changed, conflicts, err
+		}
+		if updated {
+			changed = append(changed, link)
+		}
+	}
+
+	return changed, conflicts, nil
+}
+
+// updateAlternatives loads the registry, lets change modify the group of
+// name and relinks name to its active provider.
+func updateAlternatives(baseDir, name string, force bool, change func(reg *linkRegistry, binDirs []string) error) error {
+	if err := validateName("executable", name); err != nil {
+		return err
+	}
+
+	binDirs, err := absBinDirs()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockLinkRegistry(baseDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		return err
+	}
+
+	if err := change(reg, binDirs); err != nil {
+		return err
+	}
+
+	var conflicts []string
+	if _, ok := reg.Alternatives[name]; ok {
+		if _, conflicts, err = applyAlternative(baseDir, reg, binDirs, name, "", nil, force); err != nil {
+			return err
+		}
+	}
+	if err := reg.save(baseDir); err != nil {
+		return fmt.Errorf("failed to save link registry: %w", err)
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("refusing to replace bin links: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// addAlternative registers app as a provider of name with priority. The app
+// need not be installed yet; it becomes available once its current version
+// has the executable.
+func addAlternative(baseDir, name, app string, priority int, force bool) error {
+	if err := validateName("app", app); err != nil {
+		return err
+	}
+
+	return updateAlternatives(baseDir, name, force, func(reg *linkRegistry, _ []string) error {
+		group, ok := reg.Alternatives[name]
+		if !ok {
+			group = &alternativeGroup{Providers: make(map[string]int)}
+			reg.Alternatives[name] = group
+		}
+		group.Providers[app] = priority
+		return nil
+	})
+}
+
+// removeAlternative unregisters app as a provider of name. The links app
+// holds for name go to the next provider; when app was the last provider,
+// its link for name falls back to ordinary ownership.
+func removeAlternative(baseDir, name, app string, force bool) error {
+	lastProvider := false
+	err := updateAlternatives(baseDir, name, force, func(reg *linkRegistry, binDirs []string) error {
+		if !reg.isProvider(name, app) {
+			return fmt.Errorf("%s is not a provider of %s", app, name)
+		}
+		dropProvider(reg, app, name)
+		_, remaining := reg.Alternatives[name]
+		lastProvider = !remaining
+
+		// Release app's links so they are free for the remaining providers
+		for _, binDir := range binDirs {
+			link := filepath.Join(binDir, name)
+			if !ownsLink(baseDir, reg, link, app) {
+				continue
+			}
+			if err := os.Remove(link); err != nil {
+				return fmt.Errorf("failed to remove bin symlink: %w", err)
+			}
+			delete(reg.Links, link)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if current, _ := getCurrentVersion(baseDir, app); lastProvider && current != "" {
+		_, err = syncBinLinks(baseDir, app, force)
+	}
+	return err
+}
+
+// setAlternative makes app the active provider of name until it becomes
+// unavailable. An empty app returns name to auto mode.
+func setAlternative(baseDir, name, app string, force bool) error {
+	return updateAlternatives(baseDir, name, force, func(reg *linkRegistry, _ []string) error {
+		group, ok := reg.Alternatives[name]
+		if !ok {
+			return fmt.Errorf("no alternatives registered for %s", name)
+		}
+		if app != "" {
+			if _, ok := group.Providers[app]; !ok {
+				return fmt.Errorf("%s is not a provider of %s", app, name)
+			}
+			if !providerAvailable(baseDir, app, name, "", nil) {
+				return fmt.Errorf("%s has no current version providing %s", app, name)
+			}
+		}
+		group.Manual = app
+		return nil
+	})
+}
+
+// dropProvider removes app from the providers of the given names, or of
+// every name if none are given, deleting groups left without providers.
+func dropProvider(reg *linkRegistry, app string, names ...string) {
+	if len(names) == 0 {
+		names = reg.providedBy(app)
+	}
+	for _, name := range names {
+		group, ok := reg.Alternatives[name]
+		if !ok {
+			continue
+		}
+		delete(group.Providers, app)
+		if group.Manual == app {
+			group.Manual = ""
+		}
+		if len(group.Providers) == 0 {
+			delete(reg.Alternatives, name)
+		}
+	}
+}
+
+// forgetProvider unregisters app from every alternatives group, e.g. after
+// it has been removed.
+func forgetProvider(baseDir, app string) error {
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil || len(reg.providedBy(app)) == 0 {
+		return err
+	}
+	return updateLinkRegistry(baseDir, func(reg *linkRegistry) error {
+		dropProvider(reg, app)
+		return nil
+	})
+}
+
+// listAlternatives returns the providers of name, highest priority first,
+// along with the active provider and the manually chosen one.
+func listAlternatives(baseDir, name string) ([]alternativeInfo, string, string, error) {
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		return nil, "", "", err
+	}
+	group, ok := reg.Alternatives[name]
+	if !ok {
+		return nil, "", "", fmt.Errorf("no alternatives registered for %s", name)
+	}
+
+	var providers []alternativeInfo
+	for app, priority := range group.Providers {
+		providers = append(providers, alternativeInfo{
+			app:       app,
+			priority:  priority,
+			available: providerAvailable(baseDir, app, name, "", nil),
+		})
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		if providers[i].priority != providers[j].priority {
+			return providers[i].priority > providers[j].priority
+		}
+		return providers[i].app < providers[j].app
+	})
+
+	return providers, activeProvider(baseDir, group, name, "", nil), group.Manual, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAlternatives(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	link := filepath.Join(home, ".local", "bin", "godot")
+	linkedTo := func() string {
+		data, _ := os.ReadFile(link)
+		return string(data)
+	}
+
+	installTestVersion(t, baseDir, "godot", "4.5.1", "godot")
+	if err := addAlternative(baseDir, "godot", "godot", 10, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := addAlternative(baseDir, "godot", "godot-mono", 20, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := linkedTo(); got != "godot 4.5.1" {
+		t.Errorf("unavailable provider should not take the link, got %q", got)
+	}
+
+	// Installing the higher priority provider takes over the link
+	installTestVersion(t, baseDir, "godot-mono", "4.5.1", "godot")
+	if got := linkedTo(); got != "godot-mono 4.5.1" {
+		t.Errorf("expected godot-mono, got %q", got)
+	}
+
+	// A manual choice sticks until auto mode is restored
+	if err := setAlternative(baseDir, "godot", "godot", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := linkedTo(); got != "godot 4.5.1" {
+		t.Errorf("expected godot after manual choice, got %q", got)
+	}
+	providers, active, manual, err := listAlternatives(baseDir, "godot")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(providers) != 2 || providers[0].app != "godot-mono" || active != "godot" || manual != "godot" {
+		t.Errorf("unexpected alternatives: %+v active=%s manual=%s", providers, active, manual)
+	}
+	if err := setAlternative(baseDir, "godot", "", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := linkedTo(); got != "godot-mono 4.5.1" {
+		t.Errorf("expected godot-mono in auto mode, got %q", got)
+	}
+
+	// Removing the active provider falls back to the next one
+	if _, err := removeApp(baseDir, "godot-mono", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := linkedTo(); got != "godot 4.5.1" {
+		t.Errorf("expected fallback to godot, got %q", got)
+	}
+	providers, _, _, _ = listAlternatives(baseDir, "godot")
+	if len(providers) != 1 {
+		t.Errorf("removed app should no longer be a provider: %+v", providers)
+	}
+
+	// Without providers left, the link returns to ordinary ownership
+	if err := removeAlternative(baseDir, "godot", "godot", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := linkedTo(); got != "godot 4.5.1" {
+		t.Errorf("expected godot to keep its link, got %q", got)
+	}
+	if _, _, _, err := listAlternatives(baseDir, "godot"); err == nil {
+		t.Error("expected no alternatives left")
+	}
+}
+
+func TestAlternatives_NotProvider(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+
+	if err := setAlternative(baseDir, "node", "node", false); err == nil {
+		t.Error("expected error without registered alternatives")
+	}
+	if err := addAlternative(baseDir, "node", "node", 10, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := setAlternative(baseDir, "node", "bun", false); err == nil {
+		t.Error("expected error for an app that is not a provider")
+	}
+	if err := setAlternative(baseDir, "node", "node", false); err == nil {
+		t.Error("expected error for a provider without a current version")
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// archiveFormat returns the archive format of path based on its file name,
+// or "" if path is not a supported archive.
+func archiveFormat(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".txz"):
+		return "tar.xz"
+	case strings.HasSuffix(name, ".tar"):
+		return "tar"
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	}
+	return ""
+}
+
+func isArchive(path string) bool {
+	return archiveFormat(path) != ""
+}
+
+func installArchive(baseDir, archivePath, appName, version string, force bool) error {
+	if err := validateName("app", appName); err != nil {
+		return err
+	}
+	if err := validateVersionName(version); err != nil {
+		return err
+	}
+	// Get absolute path of the archive
+	absPath, err := filepath.Abs(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	// Check if archive exists
+	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+		return fmt.Errorf("archive does not exist: %s", absPath)
+	}
+
+	staged, err := newStagingDir(baseDir, appName, version)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staged)
+
+	root, err := stageArchive(absPath, staged)
+	if err != nil {
+		return err
+	}
+
+	// Move extracted tree into place: ~/.local/share/apps/<app>/<version>/
+	if err := commitVersion(baseDir, root, appName, version); err != nil {
+		return err
+	}
+
+	return activateVersion(baseDir, appName, version, force)
+}
+
+// stageArchive extracts the archive at src into the staging directory
+// staged and returns the directory that becomes the version directory.
+func stageArchive(src, staged string) (string, error) {
+	if err := extractArchive(src, staged); err != nil {
+		return "", fmt.Errorf("failed to extract archive: %w", err)
+	}
+	return archiveRoot(staged)
+}
+
+// archiveRoot returns the directory within an extracted archive that should
+// become the version directory. A single top-level folder (like go/) is
+// stripped, and an archive holding a single executable (like the Godot
+// release zips) is laid out as bin/<executable>.
+func archiveRoot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if len(entries) == 1 && entries[0].IsDir() {
+		dir = filepath.Join(dir, entries[0].Name())
+		if entries, err = os.ReadDir(dir); err != nil {
+			return "", err
+		}
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, "bin")); err == nil && info.IsDir() {
+		return dir, nil
+	}
+
+	if len(entries) == 1 && entries[0].Type().IsRegular() {
+		name := entries[0].Name()
+		binDir := filepath.Join(dir, "bin")
+		if err := os.Mkdir(binDir, 0755); err != nil {
+			return "", err
+		}
+		if err := os.Rename(filepath.Join(dir, name), filepath.Join(binDir, name)); err != nil {
+			return "", err
+		}
+		if err := os.Chmod(filepath.Join(binDir, name), 0755); err != nil {
+			return "", err
+		}
+		return dir, nil
+	}
+
+	return "", fmt.Errorf("bin/ directory does not exist in archive")
+}
+
+// extractArchive extracts the archive at src into the existing directory dst.
+func extractArchive(src, dst string) error {
+	format := archiveFormat(src)
+	if format == "zip" {
+		return extractZip(src, dst)
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch format {
+	case "tar.gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case "tar.xz":
+		xr, err := xz.NewReader(f)
+		if err != nil {
+			return err
+		}
+		r = xr
+	case "tar":
+	default:
+		return fmt.Errorf("unsupported archive format: %s", src)
+	}
+
+	return extractTar(r, dst)
+}
+
+func extractTar(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := archiveEntryPath(dst, hdr.Name)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(hdr.Mode).Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := archiveDir(dst, target, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(dst, target, tr, mode); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := writeArchiveSymlink(dst, target, hdr.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			source, err := archiveEntryPath(dst, hdr.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := archiveDir(dst, filepath.Dir(source), 0755); err != nil {
+				return err
+			}
+			if err := archiveDir(dst, filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+		default:
+			// Skip devices, fifos and pax metadata entries
+		}
+	}
+}
+
+func extractZip(src, dst string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		target, err := archiveEntryPath(dst, f.Name)
+		if err != nil {
+			return err
+		}
+		mode := f.Mode()
+
+		switch {
+		case mode.IsDir():
+			if err := archiveDir(dst, target, mode.Perm()|0700); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			linkname, err := readZipEntry(f)
+			if err != nil {
+				return err
+			}
+			if err := writeArchiveSymlink(dst, target, linkname); err != nil {
+				return err
+			}
+		default:
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = writeArchiveFile(dst, target, rc, mode.Perm())
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func readZipEntry(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// archiveEntryPath returns the destination path of an archive entry,
+// rejecting absolute names and names that escape dst (zip-slip).
+func archiveEntryPath(dst, name string) (string, error) {
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) || !isWithin(dst, filepath.Join(dst, name)) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	return filepath.Join(dst, name), nil
+}
+
+// isWithin reports whether path is dir itself or lexically inside dir.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// archiveDir creates dir, a directory inside dst, and its missing parents.
+// Every existing component must be a real directory: following a symlink
+// created by an earlier entry of the archive could lead outside dst.
+func archiveDir(dst, dir string, mode os.FileMode) error {
+	rel, err := filepath.Rel(dst, dir)
+	if err != nil || !isWithin(dst, dir) {
+		return fmt.Errorf("illegal path in archive: %s", dir)
+	}
+	if rel == "." {
+		return nil
+	}
+
+	path := dst
+	names := strings.Split(rel, string(filepath.Separator))
+	for i, name := range names {
+		path = filepath.Join(path, name)
+		perm := os.FileMode(0755)
+		if i == len(names)-1 {
+			perm = mode
+		}
+		info, err := os.Lstat(path)
+		switch {
+		case os.IsNotExist(err):
+			if err := os.Mkdir(path, perm); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case !info.IsDir():
+			return fmt.Errorf("illegal path in archive: %s is not a directory", filepath.ToSlash(rel))
+		}
+	}
+	return nil
+}
+
+func writeArchiveFile(dst, target string, r io.Reader, mode os.FileMode) error {
+	if err := archiveDir(dst, filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	// Writing through a symlink left by an earlier entry could escape dst
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("illegal path in archive: %s is a symlink", target)
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// OpenFile applies the umask, so set the archived mode explicitly
+	return os.Chmod(target, mode)
+}
+
+// writeArchiveSymlink creates the symlink target -> linkname inside dst.
+// The link must resolve inside dst from the real path of its directory.
+// Its name may only climb with leading ".." components: in "a/..", a could
+// be a symlink, which the kernel follows before applying "..".
+func writeArchiveSymlink(dst, target, linkname string) error {
+	if err := archiveDir(dst, filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	illegal := fmt.Errorf("illegal symlink in archive: %s -> %s", target, linkname)
+	if filepath.IsAbs(linkname) || strings.HasPrefix(linkname, "/") {
+		return illegal
+	}
+	climbing := true
+	for _, name := range strings.Split(filepath.ToSlash(linkname), "/") {
+		switch {
+		case name == "..":
+			if !climbing {
+				return illegal
+			}
+		case name != "." && name != "":
+			climbing = false
+		}
+	}
+	resolved := filepath.Join(realPath(filepath.Dir(target)), filepath.FromSlash(linkname))
+	if !isWithin(realPath(dst), resolved) {
+		return illegal
+	}
+
+	return os.Symlink(linkname, target)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testArchiveEntry struct {
+	name     string
+	body     string
+	mode     int64
+	linkname string
+	dir      bool
+}
+
+func writeTestTarGz(t *testing.T, path string, entries []testArchiveEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: e.mode}
+		switch {
+		case e.dir:
+			hdr.Typeflag = tar.TypeDir
+		case e.linkname != "":
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = e.linkname
+		default:
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(e.body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestZip(t *testing.T, path string, entries []testArchiveEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name}
+		hdr.SetMode(os.FileMode(e.mode))
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveFormat(t *testing.T) {
+	tests := map[string]string{
+		"go1.25.6.linux-amd64.tar.gz": "tar.gz",
+		"app.tgz":                     "tar.gz",
+		"app.tar.xz":                  "tar.xz",
+		"Godot_v4.5.1-stable.zip":     "zip",
+		"app.tar":                     "tar",
+		"lav":                         "",
+	}
+	for name, want := range tests {
+		if got := archiveFormat(name); got != want {
+			t.Errorf("archiveFormat(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestInstallArchive_TarGz(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "go1.25.6.linux-amd64.tar.gz")
+	writeTestTarGz(t, archive, []testArchiveEntry{
+		{name: "go/", dir: true, mode: 0755},
+		{name: "go/bin/", dir: true, mode: 0755},
+		{name: "go/bin/go", body: "#!/bin/sh\n", mode: 0755},
+		{name: "go/bin/gofmt", body: "#!/bin/sh\n", mode: 0755},
+		{name: "go/VERSION", body: "go1.25.6\n", mode: 0644},
+		{name: "go/misc/version", linkname: "../VERSION"},
+	})
+
+	if err := installArchive(baseDir, archive, "go", "1.25.6", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Top-level go/ folder is stripped
+	versionDir := filepath.Join(baseDir, "go", "1.25.6")
+	info, err := os.Stat(filepath.Join(versionDir, "bin", "go"))
+	if err != nil {
+		t.Fatalf("expected bin/go to exist: %v", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755, got %v", info.Mode().Perm())
+	}
+	if info, _ := os.Stat(filepath.Join(versionDir, "VERSION")); info.Mode().Perm() != 0644 {
+		t.Errorf("expected mode 0644, got %v", info.Mode().Perm())
+	}
+
+	// Symlinks are preserved
+	target, err := os.Readlink(filepath.Join(versionDir, "misc", "version"))
+	if err != nil || target != "../VERSION" {
+		t.Errorf("expected symlink to ../VERSION, got %q (%v)", target, err)
+	}
+
+	current, _ := getCurrentVersion(baseDir, "go")
+	if current != "1.25.6" {
+		t.Errorf("expected current=1.25.6, got %s", current)
+	}
+
+	// No extract directory is left behind
+	versions, _ := listVersions(baseDir, "go")
+	if len(versions) != 1 {
+		t.Errorf("expected 1 version, got %v", versions)
+	}
+}
+
+func TestInstallArchive_SingleBinaryZip(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "Godot_v4.5.1-stable_linux.x86_64.zip")
+	writeTestZip(t, archive, []testArchiveEntry{
+		{name: "Godot_v4.5.1-stable_linux.x86_64", body: "ELF", mode: 0755},
+	})
+
+	if err := installArchive(baseDir, archive, "godot", "4.5.1", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	binPath := filepath.Join(baseDir, "godot", "4.5.1", "bin", "Godot_v4.5.1-stable_linux.x86_64")
+	if _, err := os.Stat(binPath); err != nil {
+		t.Errorf("expected binary in bin/: %v", err)
+	}
+}
+
+func TestInstallArchive_ZipSlip(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	outside := filepath.Join(baseDir, "evil")
+
+	archive := filepath.Join(t.TempDir(), "evil.zip")
+	writeTestZip(t, archive, []testArchiveEntry{
+		{name: "app/bin/app", body: "x", mode: 0755},
+		{name: "../../evil", body: "x", mode: 0644},
+	})
+
+	if err := installArchive(baseDir, archive, "app", "1.0.0", false); err == nil {
+		t.Error("expected error for zip-slip path")
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Error("file outside the extract directory should not be written")
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "app", "1.0.0")); !os.IsNotExist(err) {
+		t.Error("version directory should not be created")
+	}
+}
+
+func TestInstallArchive_EscapingSymlink(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "evil.tar.gz")
+	writeTestTarGz(t, archive, []testArchiveEntry{
+		{name: "app/bin/app", body: "x", mode: 0755},
+		{name: "app/link", linkname: "../../../etc"},
+	})
+
+	if err := installArchive(baseDir, archive, "app", "1.0.0", false); err == nil {
+		t.Error("expected error for escaping symlink")
+	}
+}
+
+func TestInstallArchive_SymlinkChain(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "evil.tar.gz")
+	// Each link stays inside the archive on its own, but following them
+	// in turn climbs out of the extract directory
+	writeTestTarGz(t, archive, []testArchiveEntry{
+		{name: "bin/app", body: "x", mode: 0755},
+		{name: "sub/a", linkname: ".."},
+		{name: "sub/a/b", linkname: ".."},
+		{name: "sub/a/b/ESCAPED", body: "x", mode: 0644},
+	})
+
+	if err := installArchive(baseDir, archive, "app", "1.0.0", false); err == nil {
+		t.Error("expected error for a path through a symlink")
+	}
+	filepath.WalkDir(baseDir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.Name() == "ESCAPED" {
+			t.Errorf("file written through symlinks: %s", path)
+		}
+		return nil
+	})
+}
+
+func TestWriteArchiveSymlink_ClimbAfterName(t *testing.T) {
+	dst := t.TempDir()
+	// Creating "self -> ." is fine, but "up -> self/.." would escape dst
+	// once the kernel resolves self first
+	if err := writeArchiveSymlink(dst, filepath.Join(dst, "self"), "."); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeArchiveSymlink(dst, filepath.Join(dst, "up"), "self/.."); err == nil {
+		t.Error("expected error for .. after a name")
+	}
+	if err := writeArchiveSymlink(dst, filepath.Join(dst, "dir", "up"), "../self"); err != nil {
+		t.Errorf("leading .. inside dst should be allowed: %v", err)
+	}
+}
+
+func TestInstallArchive_NoBinDir(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "docs.tar.gz")
+	writeTestTarGz(t, archive, []testArchiveEntry{
+		{name: "docs/a.txt", body: "a", mode: 0644},
+		{name: "docs/b.txt", body: "b", mode: 0644},
+	})
+
+	if err := installArchive(baseDir, archive, "docs", "1.0.0", false); err == nil {
+		t.Error("expected error for archive without bin/")
+	}
+}
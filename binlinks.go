@@ -0,0 +1,379 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// binLinkTarget returns the symlink target for executable name of appName
+// as seen from binDir. The target is relative when binDir and the lav root
+// share a top-level directory (the default ~/.local/bin and
+// ~/.local/share/lav do), so the links survive moving both together, and
+// absolute otherwise, e.g. for a root on a separate data volume.
+func binLinkTarget(binDir, baseDir, appName, name string) (string, error) {
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(absBase, appName, "current", "bin", name)
+
+	// Relative targets are resolved from the real bin directory, so compute
+	// them from the real paths in case either directory is a symlink
+	realBin := realPath(binDir)
+	realTarget := filepath.Join(realPath(absBase), appName, "current", "bin", name)
+	if topLevelDir(realBin) != topLevelDir(realTarget) {
+		return target, nil
+	}
+
+	rel, err := filepath.Rel(realBin, realTarget)
+	if err != nil {
+		return target, nil
+	}
+	return rel, nil
+}
+
+// realPath returns path made absolute with symlinks resolved, or just
+// absolute if it does not exist.
+func realPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+// topLevelDir returns the first component of an absolute path, e.g. "home"
+// for /home/user/.local/bin.
+func topLevelDir(path string) string {
+	rest := strings.TrimPrefix(path, string(filepath.Separator))
+	first, _, _ := strings.Cut(rest, string(filepath.Separator))
+	return first
+}
+
+// readLinkTarget returns the absolute path a symlink points to, without
+// requiring the target to exist.
+func readLinkTarget(link string) (string, error) {
+	target, err := os.Readlink(link)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(realPath(filepath.Dir(link)), target)
+	}
+	return target, nil
+}
+
+// linkPointsInto reports whether the symlink link points into dir.
+func linkPointsInto(link, dir string) bool {
+	target, err := readLinkTarget(link)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	return isWithin(absDir, target) || isWithin(realPath(absDir), target)
+}
+
+// appExecutables returns the names of the executables in the bin/
+// directory of version of appName. A version without bin/ has none.
+func appExecutables(baseDir, appName, version string) (map[string]bool, error) {
+	binDir := filepath.Join(baseDir, appName, version, "bin")
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read bin directory: %w", err)
+	}
+
+	executables := make(map[string]bool)
+	for _, entry := range entries {
+		info, err := os.Stat(filepath.Join(binDir, entry.Name()))
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+		executables[entry.Name()] = true
+	}
+	return executables, nil
+}
+
+// absBinDirs returns the bin directories as absolute paths, which is how
+// links are keyed in the registry.
+func absBinDirs() ([]string, error) {
+	binDirs, err := getBinDirs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bin directory: %w", err)
+	}
+
+	dirs := make([]string, len(binDirs))
+	for i, dir := range binDirs {
+		if dirs[i], err = filepath.Abs(dir); err != nil {
+			return nil, err
+		}
+	}
+	return dirs, nil
+}
+
+// linkConflict returns why link cannot be pointed at appName, or "" if it
+// can. Files that are not symlinks are never replaced; links owned by other
+// apps or not managed by lav are only taken over with force.
+func linkConflict(baseDir string, reg *linkRegistry, link, appName string, force bool) string {
+	info, err := os.Lstat(link)
+	if err != nil {
+		return ""
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Sprintf("%s exists and is not a symlink", link)
+	}
+	if force {
+		return ""
+	}
+
+	switch owner := reg.owner(baseDir, link); owner {
+	case appName:
+		return ""
+	case "":
+		return fmt.Sprintf("%s is not managed by lav (use --force to replace it)", link)
+	default:
+		return fmt.Sprintf("%s is owned by %s (use --force to take it over)", link, owner)
+	}
+}
+
+// checkBinLinks reports an error if linking the executables of version of
+// appName would replace a link or file that appName does not own.
+// Executables appName provides through alternatives are not checked, since
+// the active provider decides their links. Callers hold the registry lock
+// until the links are synced.
+func checkBinLinks(baseDir string, reg *linkRegistry, binDirs []string, appName, version string, force bool) error {
+	executables, err := appExecutables(baseDir, appName, version)
+	if err != nil {
+		return err
+	}
+
+	var conflicts []string
+	for _, binDir := range binDirs {
+		for _, name := range slices.Sorted(maps.Keys(executables)) {
+			if reg.isProvider(name, appName) {
+				continue
+			}
+			if conflict := linkConflict(baseDir, reg, filepath.Join(binDir, name), appName, force); conflict != "" {
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("refusing to replace bin links: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// syncBinLinks makes the links of appName in each bin directory match the
+// executables of its current version. It returns the links that changed.
+func syncBinLinks(baseDir, appName string, force bool) ([]string, error) {
+	executables, err := appExecutables(baseDir, appName, "current")
+	if err != nil {
+		return nil, err
+	}
+	return reconcileBinLinks(baseDir, appName, executables, force)
+}
+
+// reconcileBinLinks creates or updates a link in each bin directory for
+// every name in executables and removes the other links appName owns,
+// recording ownership in the link registry. Executables appName provides
+// through alternatives are linked to whichever provider is active.
+// Conflicting links are skipped and reported in the returned error after
+// everything else is done.
+func reconcileBinLinks(baseDir, appName string, executables map[string]bool, force bool) ([]string, error) {
+	binDirs, err := absBinDirs()
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockLinkRegistry(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := reconcileLinks(baseDir, reg, binDirs, appName, executables, force)
+	// Save what was done even on error, so the registry matches the links
+	if saveErr := reg.save(baseDir); saveErr != nil && err == nil {
+		err = fmt.Errorf("failed to save link registry: %w", saveErr)
+	}
+	return changed, err
+}
+
+// reconcileLinks does the work of reconcileBinLinks on reg, which the
+// caller loaded under the registry lock and saves afterwards.
+func reconcileLinks(baseDir string, reg *linkRegistry, binDirs []string, appName string, executables map[string]bool, force bool) ([]string, error) {
+	var changed, conflicts []string
+	for _, binDir := range binDirs {
+		entries, err := os.ReadDir(binDir)
+		if err != nil && !os.IsNotExist(err) {
+			return changed, err
+		}
+
+		// Remove links to executables the current version does not have
+		for _, entry := range entries {
+			link := filepath.Join(binDir, entry.Name())
+			if executables[entry.Name()] || reg.isProvider(entry.Name(), appName) || !ownsLink(baseDir, reg, link, appName) {
+				continue
+			}
+			if err := os.Remove(link); err != nil {
+				return changed, fmt.Errorf("failed to remove bin symlink: %w", err)
+			}
+			delete(reg.Links, link)
+			changed = append(changed, link)
+		}
+
+		if len(executables) == 0 {
+			continue
+		}
+		// Create the bin directory if it doesn't exist
+		if err := os.MkdirAll(binDir, 0755); err != nil {
+			return changed, fmt.Errorf("failed to create %s: %w", binDir, err)
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(executables)) {
+			if reg.isProvider(name, appName) {
+				continue
+			}
+			link := filepath.Join(binDir, name)
+			if conflict := linkConflict(baseDir, reg, link, appName, force); conflict != "" {
+				conflicts = append(conflicts, conflict)
+				continue
+			}
+
+			updated, err := pointLink(baseDir, reg, binDir, appName, name)
+			if err != nil {
+				return changed, err
+			}
+			if updated {
+				changed = append(changed, link)
+			}
+		}
+	}
+
+	// Shared executables go to the active provider, which may now be another app
+	for _, name := range reg.providedBy(appName) {
+		altChanged, altConflicts, err := applyAlternative(baseDir, reg, binDirs, name, appName, executables, force)
+		changed = append(changed, altChanged...)
+		conflicts = append(conflicts, altConflicts...)
+		if err != nil {
+			return changed, err
+		}
+	}
+
+	// Forget links of appName that no longer exist
+	for link, owner := range reg.Links {
+		if _, err := os.Lstat(link); owner == appName && os.IsNotExist(err) {
+			delete(reg.Links, link)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return changed, fmt.Errorf("refusing to replace bin links: %s", strings.Join(conflicts, "; "))
+	}
+	return changed, nil
+}
+
+// pointLink points the link for executable name in binDir at appName's
+// current version and records appName as its owner. It reports whether the
+// link changed. Callers check for conflicts first.
+func pointLink(baseDir string, reg *linkRegistry, binDir, appName, name string) (bool, error) {
+	link := filepath.Join(binDir, name)
+	target, err := appLinkTarget(binDir, baseDir, appName, name)
+	if err != nil {
+		return false, err
+	}
+	reg.Links[link] = appName
+	if old, err := os.Readlink(link); err == nil && old == target {
+		return false, nil
+	}
+
+	if err := replaceSymlink(target, link); err != nil {
+		return false, fmt.Errorf("failed to update bin symlink for %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// binLinkInfo describes a link found by lav which.
+type binLinkInfo struct {
+	link    string
+	target  string
+	app     string // owning app, "" if lav does not manage the link
+	version string // version of app the link runs
+	source  string // where version comes from, as for lav current
+	file    string // file the link runs, "" if it is dangling
+	shim    bool
+}
+
+// whichBin returns the links named name in the bin directories, in order,
+// resolved to the file each one runs in dir.
+func whichBin(baseDir, name, dir string) ([]binLinkInfo, error) {
+	if err := validateName("executable", name); err != nil {
+		return nil, err
+	}
+
+	binDirs, err := absBinDirs()
+	if err != nil {
+		return nil, err
+	}
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []binLinkInfo
+	for _, binDir := range binDirs {
+		link := filepath.Join(binDir, name)
+		if _, err := os.Lstat(link); err != nil {
+			continue
+		}
+
+		found = append(found, resolveBinLink(baseDir, reg, link, dir))
+	}
+
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%s is not in any bin directory", name)
+	}
+	return found, nil
+}
+
+// relinkApps rewrites the bin links of every app with a current version so
+// that they point into baseDir, e.g. after the lav root has been moved. With
+// force, links lav does not recognize as its own are replaced too. It returns
+// the links that were changed.
+func relinkApps(baseDir string, force bool) ([]string, error) {
+	apps, err := listApps(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, app := range apps {
+		if current, _ := getCurrentVersion(baseDir, app); current == "" {
+			continue
+		}
+
+		appChanged, err := syncBinLinks(baseDir, app, force)
+		changed = append(changed, appChanged...)
+		if err != nil {
+			return changed, err
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinLinkTarget_DefaultLayoutIsRelative(t *testing.T) {
+	home := t.TempDir()
+	binDir := filepath.Join(home, ".local", "bin")
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+
+	target, err := binLinkTarget(binDir, baseDir, "go", "gofmt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := filepath.Join("..", "share", "lav", "go", "current", "bin", "gofmt")
+	if target != want {
+		t.Errorf("expected %s, got %s", want, target)
+	}
+}
+
+func TestBinLinkTarget_OtherTopLevelIsAbsolute(t *testing.T) {
+	binDir := filepath.Join(t.TempDir(), "bin")
+	baseDir := "/opt/lav"
+
+	target, err := binLinkTarget(binDir, baseDir, "go", "go")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "/opt/lav/go/current/bin/go"; target != want {
+		t.Errorf("expected %s, got %s", want, target)
+	}
+}
+
+func TestCreateBinSymlinks_LavRoot(t *testing.T) {
+	home := setupTestEnv(t)
+	// A root outside HOME, as with LAV_ROOT or XDG_DATA_HOME
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.23.0", "go")
+
+	link := filepath.Join(home, ".local", "bin", "go")
+	data, err := os.ReadFile(link)
+	if err != nil {
+		t.Fatalf("bin link does not resolve: %v", err)
+	}
+	if string(data) != "go 1.23.0" {
+		t.Errorf("bin link points to the wrong file: %q", data)
+	}
+	if !linkPointsInto(link, filepath.Join(baseDir, "go")) {
+		t.Error("bin link should point into the lav root")
+	}
+}
+
+func TestRelinkApps(t *testing.T) {
+	home := setupTestEnv(t)
+	oldRoot := filepath.Join(t.TempDir(), "lav")
+	installTestVersion(t, oldRoot, "go", "1.23.0", "go", "gofmt")
+
+	// Move the root; the old links now dangle
+	newRoot := filepath.Join(t.TempDir(), "lav")
+	if err := os.Rename(oldRoot, newRoot); err != nil {
+		t.Fatal(err)
+	}
+	binDir := filepath.Join(home, ".local", "bin")
+	if _, err := os.Stat(filepath.Join(binDir, "go")); err == nil {
+		t.Fatal("expected the old link to dangle after moving the root")
+	}
+
+	changed, err := relinkApps(newRoot, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changed) != 2 {
+		t.Errorf("expected 2 links changed, got %v", changed)
+	}
+	for _, name := range []string{"go", "gofmt"} {
+		if !linkPointsInto(filepath.Join(binDir, name), newRoot) {
+			t.Errorf("bin link %s should point into the new root", name)
+		}
+		if _, err := os.Stat(filepath.Join(binDir, name)); err != nil {
+			t.Errorf("bin link %s does not resolve: %v", name, err)
+		}
+	}
+
+	// Nothing to do the second time
+	changed, err = relinkApps(newRoot, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("expected no links changed, got %v", changed)
+	}
+}
+
+func TestSwitchVersion_SyncsBinLinks(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	binDir := filepath.Join(home, ".local", "bin")
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.0", "go", "foo")
+
+	// Files that lav does not own are never touched
+	os.WriteFile(filepath.Join(baseDir, "go", "1.23.0", "bin", "README"), []byte("docs"), 0644)
+	os.Symlink("/usr/bin/env", filepath.Join(binDir, "env"))
+	os.WriteFile(filepath.Join(binDir, "script"), []byte("mine"), 0755)
+
+	if err := switchVersion(baseDir, "go", "1.22.0", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(binDir, "foo")); !os.IsNotExist(err) {
+		t.Error("link to foo should be removed when switching to a version without it")
+	}
+	if data, err := os.ReadFile(filepath.Join(binDir, "go")); err != nil || string(data) != "go 1.22.0" {
+		t.Errorf("go should point to 1.22.0, got %q (%v)", data, err)
+	}
+
+	if err := switchVersion(baseDir, "go", "1.23.0", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(binDir, "foo")); err != nil || string(data) != "go 1.23.0" {
+		t.Errorf("foo should be linked again, got %q (%v)", data, err)
+	}
+	if _, err := os.Lstat(filepath.Join(binDir, "README")); !os.IsNotExist(err) {
+		t.Error("non-executable files should not be linked")
+	}
+	if target, _ := os.Readlink(filepath.Join(binDir, "env")); target != "/usr/bin/env" {
+		t.Errorf("foreign link should be kept, got %q", target)
+	}
+	if data, _ := os.ReadFile(filepath.Join(binDir, "script")); string(data) != "mine" {
+		t.Error("unrelated file should be kept")
+	}
+}
+
+func TestActivateVersion_LinkCollision(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	binDir := filepath.Join(home, ".local", "bin")
+	installTestVersion(t, baseDir, "python3", "3.12.0", "python")
+
+	// Another app shipping the same executable does not steal the link
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	os.WriteFile(filepath.Join(src, "bin", "python"), []byte("python2"), 0755)
+	if err := installDirectory(baseDir, src, "python2", "2.7.18", false); err == nil {
+		t.Fatal("expected error for a link owned by another app")
+	}
+	if data, _ := os.ReadFile(filepath.Join(binDir, "python")); string(data) != "python3 3.12.0" {
+		t.Errorf("link should still point to python3, got %q", data)
+	}
+	if current, _ := getCurrentVersion(baseDir, "python2"); current != "" {
+		t.Errorf("python2 should not be activated, got %s", current)
+	}
+
+	// --force takes it over
+	if err := switchVersion(baseDir, "python2", "2.7.18", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(binDir, "python")); string(data) != "python2" {
+		t.Errorf("link should point to python2, got %q", data)
+	}
+	links, err := whichBin(baseDir, "python", t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(links) != 1 || links[0].app != "python2" || links[0].version != "2.7.18" {
+		t.Errorf("unexpected owner: %+v", links)
+	}
+
+	// Removing the previous owner leaves the taken-over link alone
+	if _, err := removeApp(baseDir, "python3", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(binDir, "python")); string(data) != "python2" {
+		t.Errorf("link should still point to python2, got %q", data)
+	}
+}
+
+func TestActivateVersion_NeverReplacesFiles(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	binDir := filepath.Join(home, ".local", "bin")
+	os.MkdirAll(binDir, 0755)
+	os.WriteFile(filepath.Join(binDir, "tool"), []byte("mine"), 0755)
+
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	os.WriteFile(filepath.Join(src, "bin", "tool"), []byte("lav"), 0755)
+	if err := installDirectory(baseDir, src, "tool", "1.0.0", true); err == nil {
+		t.Fatal("expected error for a regular file in the bin directory")
+	}
+	if data, _ := os.ReadFile(filepath.Join(binDir, "tool")); string(data) != "mine" {
+		t.Errorf("regular file should be kept, got %q", data)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// parseChecksums parses a SHA256SUMS file and returns a map of file name to
+// lowercase hex digest. Both the GNU coreutils format ("<hex>  <name>", with
+// "*" marking binary mode) and the BSD format ("SHA256 (<name>) = <hex>")
+// are accepted.
+func parseChecksums(r io.Reader) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var sum, name string
+		if rest, ok := strings.CutPrefix(line, "SHA256 ("); ok {
+			before, after, found := strings.Cut(rest, ") = ")
+			if !found {
+				return nil, fmt.Errorf("invalid checksum line: %s", line)
+			}
+			name, sum = before, after
+		} else {
+			fields := strings.SplitN(line, " ", 2)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("invalid checksum line: %s", line)
+			}
+			sum = fields[0]
+			name = strings.TrimPrefix(strings.TrimLeft(fields[1], " "), "*")
+		}
+
+		sum = strings.ToLower(sum)
+		if !isSHA256Hex(sum) {
+			return nil, fmt.Errorf("invalid sha256 checksum: %s", sum)
+		}
+		sums[name] = sum
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// loadChecksums reads a SHA256SUMS file from a local path or an HTTP(S) URL.
+func loadChecksums(src string) (map[string]string, error) {
+	data, err := readFileOrURL(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+	return parseChecksums(bytes.NewReader(data))
+}
+
+// lookupChecksum finds the checksum for name, also matching entries that
+// carry a directory prefix such as "./dist/<name>".
+func lookupChecksum(sums map[string]string, name string) (string, bool) {
+	if sum, ok := sums[name]; ok {
+		return sum, true
+	}
+	for entry, sum := range sums {
+		if path.Base(entry) == name {
+			return sum, true
+		}
+	}
+	return "", false
+}
+
+// expectedSHA256 returns the digest the artifact named name must match
+// according to opts, or "" if no checksum was requested.
+func expectedSHA256(opts installOptions, name string) (string, error) {
+	var expected string
+	if opts.sha256 != "" {
+		expected = strings.ToLower(opts.sha256)
+		if !isSHA256Hex(expected) {
+			return "", fmt.Errorf("invalid sha256 checksum: %s", opts.sha256)
+		}
+	}
+
+	if opts.checksums != "" {
+		sums, err := loadChecksums(opts.checksums)
+		if err != nil {
+			return "", err
+		}
+		sum, ok := lookupChecksum(sums, name)
+		if !ok {
+			return "", fmt.Errorf("no checksum for %s in %s", name, opts.checksums)
+		}
+		if expected != "" && expected != sum {
+			return "", fmt.Errorf("checksum for %s in %s does not match --sha256", name, opts.checksums)
+		}
+		expected = sum
+	}
+
+	return expected, nil
+}
+
+func verifySHA256(path, expected string) error {
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("failed to compute checksum: %w", err)
+	}
+	if sum != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path, expected, sum)
+	}
+	return nil
+}
+
+func isSHA256Hex(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseChecksums(t *testing.T) {
+	a := strings.Repeat("a", 64)
+	b := strings.Repeat("b", 64)
+	c := strings.Repeat("C", 64)
+	input := a + "  go1.25.6.linux-amd64.tar.gz\n" +
+		"# comment\n" +
+		b + " *app.zip\n" +
+		"SHA256 (tool) = " + c + "\n"
+
+	sums, err := parseChecksums(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"go1.25.6.linux-amd64.tar.gz": a,
+		"app.zip":                     b,
+		"tool":                        strings.ToLower(c),
+	}
+	for name, want := range expected {
+		if sums[name] != want {
+			t.Errorf("sums[%q] = %q, want %q", name, sums[name], want)
+		}
+	}
+}
+
+func TestParseChecksums_Invalid(t *testing.T) {
+	if _, err := parseChecksums(strings.NewReader("nothex  file\n")); err == nil {
+		t.Error("expected error for invalid checksum")
+	}
+}
+
+func TestLookupChecksum_DirectoryPrefix(t *testing.T) {
+	sums := map[string]string{"./dist/app.tar.gz": "abc"}
+	if sum, ok := lookupChecksum(sums, "app.tar.gz"); !ok || sum != "abc" {
+		t.Errorf("expected abc, got %q (%v)", sum, ok)
+	}
+}
+
+func TestInstallPath_SHA256(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	binary := filepath.Join(t.TempDir(), "tool")
+	os.WriteFile(binary, []byte("tool contents"), 0755)
+	sum := sha256Hex([]byte("tool contents"))
+
+	// Matching checksum installs
+	if err := installPath(baseDir, binary, "tool", "1.0.0", installOptions{sha256: strings.ToUpper(sum)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Mismatching checksum fails without creating the version
+	wrong := strings.Repeat("0", 64)
+	if err := installPath(baseDir, binary, "tool", "2.0.0", installOptions{sha256: wrong}); err == nil {
+		t.Error("expected checksum mismatch error")
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "tool", "2.0.0")); !os.IsNotExist(err) {
+		t.Error("version directory should not exist after a failed verification")
+	}
+}
+
+func TestInstallPath_ChecksumsFile(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	srcDir := t.TempDir()
+	binary := filepath.Join(srcDir, "tool")
+	os.WriteFile(binary, []byte("tool contents"), 0755)
+
+	sumsFile := filepath.Join(srcDir, "SHA256SUMS")
+	os.WriteFile(sumsFile, []byte(sha256Hex([]byte("tool contents"))+"  tool\n"), 0644)
+
+	if err := installPath(baseDir, binary, "tool", "1.0.0", installOptions{checksums: sumsFile}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An artifact missing from the checksums file is rejected
+	other := filepath.Join(srcDir, "other")
+	os.WriteFile(other, []byte("other"), 0755)
+	if err := installPath(baseDir, other, "other", "1.0.0", installOptions{checksums: sumsFile}); err == nil {
+		t.Error("expected error for artifact without checksum")
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "other")); !os.IsNotExist(err) {
+		t.Error("app directory should not exist after a failed verification")
+	}
+}
+
+func TestInstallPath_ChecksumDirectory(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	srcDir := t.TempDir()
+	os.MkdirAll(filepath.Join(srcDir, "bin"), 0755)
+
+	if err := installPath(baseDir, srcDir, "app", "1.0.0", installOptions{sha256: strings.Repeat("0", 64)}); err == nil {
+		t.Error("expected error for checksum on a directory")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// commandNames lists the commands offered by shell completion.
+var commandNames = []string{
+	"install", "use", "list", "current", "info", "verify", "local", "exec", "shell", "remove",
+	"prune", "relink", "which", "owner", "alternatives", "trust", "doctor", "init", "help",
+}
+
+// completeWords returns the completion candidates for the next argument
+// after words, the arguments already typed (without "lav").
+func completeWords(baseDir string, words []string) []string {
+	if slices.Contains(words, "--") {
+		return nil
+	}
+
+	var args []string
+	for _, word := range words {
+		if !strings.HasPrefix(word, "-") {
+			args = append(args, word)
+		}
+	}
+	if len(args) == 0 {
+		return commandNames
+	}
+
+	apps, _ := listApps(baseDir)
+	versions := func(app string) []string {
+		list, _ := listVersions(baseDir, app)
+		return list
+	}
+
+	command, n := args[0], len(args)-1
+	switch command {
+	case "help":
+		if n == 0 {
+			return commandNames
+		}
+	case "use", "remove", "uninstall", "local", "info", "verify":
+		if n == 0 {
+			return apps
+		}
+		if n == 1 {
+			return versions(args[1])
+		}
+	case "shell":
+		if n == 0 {
+			return apps
+		}
+		if n == 1 && !strings.Contains(args[1], "@") {
+			return versions(args[1])
+		}
+	case "list", "current", "prune", "exec", "run":
+		if n == 0 || command == "exec" || command == "run" {
+			return apps
+		}
+	case "install":
+		if n == 1 {
+			return apps
+		}
+	case "which", "alternatives":
+		if n == 0 {
+			reg, err := loadLinkRegistry(baseDir)
+			if err != nil {
+				return nil
+			}
+			names := make(map[string]bool)
+			for link := range reg.Links {
+				names[filepath.Base(link)] = true
+			}
+			for name := range reg.Alternatives {
+				names[name] = true
+			}
+			return slices.Sorted(maps.Keys(names))
+		}
+		if command == "alternatives" && n == 1 {
+			return apps
+		}
+	case "trust":
+		if n == 0 {
+			return []string{"add", "list", "remove"}
+		}
+		if n == 1 {
+			return apps
+		}
+	case "init":
+		if n == 0 {
+			return initShells
+		}
+	}
+	return nil
+}
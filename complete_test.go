@@ -0,0 +1,35 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompleteWords(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.4", "go")
+	installTestVersion(t, baseDir, "node", "22.1.0", "node")
+
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{nil, commandNames},
+		{[]string{"use"}, []string{"go", "node"}},
+		{[]string{"use", "go"}, []string{"1.22.0", "1.23.4"}},
+		{[]string{"use", "--force", "go"}, []string{"1.22.0", "1.23.4"}},
+		{[]string{"use", "go", "1.22.0"}, nil},
+		{[]string{"shell", "go@1.22.0"}, nil},
+		{[]string{"exec", "go@1.22.0"}, []string{"go", "node"}},
+		{[]string{"exec", "go@1.22.0", "--"}, nil},
+		{[]string{"which"}, []string{"go", "node"}},
+		{[]string{"init"}, initShells},
+	}
+	for _, tt := range tests {
+		if got := completeWords(baseDir, tt.words); !slices.Equal(got, tt.want) {
+			t.Errorf("completeWords(%v): expected %v, got %v", tt.words, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// config is the user configuration read from config.toml.
+type config struct {
+	// Projects lists project directories whose version files protect
+	// versions from lav prune
+	Projects []string
+	// BinDirs lists the directories where executables are linked
+	BinDirs []string
+	Apps    map[string]appConfig
+}
+
+// appConfig holds the settings of an [apps.<name>] table.
+type appConfig struct {
+	Keep      int      // versions to keep when pruning, 0 if unset
+	OlderThan string   // only prune versions older than this, e.g. "90d"
+	Pinned    []string // versions that are never pruned
+	Mode      string   // link mode, modeSymlink or modeShim; "" means symlink
+	// Env holds environment variables set by lav exec; {version} and
+	// {prefix} in values expand to the version and its directory
+	Env map[string]string
+}
+
+// getConfigPath returns the path of the config file.
+func getConfigPath() (string, error) {
+	// 1. LAV_CONFIG environment variable (highest priority)
+	if lavConfig := os.Getenv("LAV_CONFIG"); lavConfig != "" {
+		return lavConfig, nil
+	}
+
+	// 2. XDG_CONFIG_HOME environment variable
+	if xdgConfigHome := os.Getenv("XDG_CONFIG_HOME"); xdgConfigHome != "" {
+		return filepath.Join(xdgConfigHome, "lav", "config.toml"), nil
+	}
+
+	// 3. Fallback to ~/.config/lav/config.toml
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".config", "lav", "config.toml"), nil
+}
+
+// loadConfig reads the config file. A missing file yields an empty config.
+func loadConfig() (config, error) {
+	cfg := config{Apps: make(map[string]appConfig)}
+
+	path, err := getConfigPath()
+	if err != nil {
+		return cfg, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	defer f.Close()
+
+	tables, err := parseTOML(f)
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for key, value := range tables[""] {
+		switch key {
+		case "projects":
+			if cfg.Projects, err = tomlStrings(key, value); err != nil {
+				return cfg, fmt.Errorf("%s: %w", path, err)
+			}
+		case "bin_dir":
+			dirs, err := tomlStrings(key, value)
+			if err != nil {
+				return cfg, fmt.Errorf("%s: %w", path, err)
+			}
+			for _, dir := range dirs {
+				cfg.BinDirs = append(cfg.BinDirs, expandHome(dir))
+			}
+		}
+	}
+
+	for table, values := range tables {
+		app, ok := strings.CutPrefix(table, "apps.")
+		if !ok {
+			continue
+		}
+
+		// [apps.<name>.env] sets environment variables for lav exec
+		if app, ok := strings.CutSuffix(app, ".env"); ok {
+			ac := cfg.Apps[app]
+			ac.Env = make(map[string]string)
+			for key, value := range values {
+				s, ok := value.(string)
+				if !ok {
+					return cfg, fmt.Errorf("%s: [%s] %s must be a string", path, table, key)
+				}
+				ac.Env[key] = s
+			}
+			cfg.Apps[app] = ac
+			continue
+		}
+
+		ac := cfg.Apps[app]
+		for key, value := range values {
+			switch key {
+			case "keep":
+				n, ok := value.(int64)
+				if !ok || n < 0 {
+					return cfg, fmt.Errorf("%s: [%s] keep must be a non-negative integer", path, table)
+				}
+				ac.Keep = int(n)
+			case "older_than":
+				s, ok := value.(string)
+				if !ok {
+					return cfg, fmt.Errorf("%s: [%s] older_than must be a string", path, table)
+				}
+				ac.OlderThan = s
+			case "mode":
+				s, ok := value.(string)
+				if !ok || (s != modeSymlink && s != modeShim) {
+					return cfg, fmt.Errorf("%s: [%s] mode must be %q or %q", path, table, modeSymlink, modeShim)
+				}
+				ac.Mode = s
+			case "pinned":
+				if ac.Pinned, err = tomlStrings(key, value); err != nil {
+					return cfg, fmt.Errorf("%s: [%s] %w", path, table, err)
+				}
+			}
+		}
+		cfg.Apps[app] = ac
+	}
+
+	return cfg, nil
+}
+
+func tomlStrings(key string, value any) ([]string, error) {
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case string:
+		return []string{v}, nil
+	}
+	return nil, fmt.Errorf("%s must be a string or array of strings", key)
+}
+
+// parseTOML parses the subset of TOML used by lav's config and project
+// files: [table] headers and key = value pairs whose values are strings,
+// integers, booleans or single-line arrays of strings. It returns the values
+// of each table keyed by the table name, with top-level keys under "".
+// Anything outside the subset is an error naming its line.
+func parseTOML(r io.Reader) (map[string]map[string]any, error) {
+	tables := map[string]map[string]any{"": {}}
+	table := ""
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") {
+			return nil, fmt.Errorf("line %d: arrays of tables are not supported", lineNo)
+		}
+		if strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 || !isTOMLComment(line[end+1:]) {
+				return nil, fmt.Errorf("line %d: invalid table header", lineNo)
+			}
+			name, err := parseTOMLTableName(line[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			if _, ok := tables[name]; ok {
+				return nil, fmt.Errorf("line %d: table [%s] is defined twice", lineNo, name)
+			}
+			table = name
+			tables[table] = make(map[string]any)
+			continue
+		}
+
+		key, rest, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key, err := parseTOMLKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if _, ok := tables[table][key]; ok {
+			return nil, fmt.Errorf("line %d: %s is defined twice", lineNo, key)
+		}
+
+		value, rest, err := parseTOMLValue(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if !isTOMLComment(rest) {
+			return nil, fmt.Errorf("line %d: unexpected %q after value", lineNo, strings.TrimSpace(rest))
+		}
+		tables[table][key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// parseTOMLTableName parses the name between the brackets of a table
+// header: bare or quoted keys separated by dots, e.g. apps."my-app".env.
+func parseTOMLTableName(s string) (string, error) {
+	var parts []string
+	for _, part := range splitTOMLDotted(s) {
+		key, err := parseTOMLKey(part)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, key)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// splitTOMLDotted splits s at the dots outside quotes.
+func splitTOMLDotted(s string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '.':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseTOMLKey parses a single bare or quoted key. Dotted keys are not
+// supported outside table headers.
+func parseTOMLKey(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+		key, rest, err := parseTOMLString(s)
+		if err != nil || rest != "" {
+			return "", fmt.Errorf("invalid key %s", s)
+		}
+		return key, nil
+	}
+
+	if s == "" {
+		return "", fmt.Errorf("missing key")
+	}
+	for _, c := range s {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			if c == '.' {
+				return "", fmt.Errorf("dotted key %s is not supported; use a [table] header", s)
+			}
+			return "", fmt.Errorf("invalid key %s", s)
+		}
+	}
+	return s, nil
+}
+
+// parseTOMLValue parses the value at the start of s and returns it with the
+// remainder of s.
+func parseTOMLValue(s string) (any, string, error) {
+	switch {
+	case strings.HasPrefix(s, `"""`), strings.HasPrefix(s, "'''"):
+		return nil, "", fmt.Errorf("multi-line strings are not supported")
+
+	case strings.HasPrefix(s, `"`), strings.HasPrefix(s, "'"):
+		return parseTOMLString(s)
+
+	case strings.HasPrefix(s, "{"):
+		return nil, "", fmt.Errorf("inline tables are not supported; use a [table] header")
+
+	case strings.HasPrefix(s, "["):
+		var values []string
+		rest := strings.TrimSpace(s[1:])
+		for !strings.HasPrefix(rest, "]") {
+			if rest == "" || strings.HasPrefix(rest, "#") {
+				return nil, "", fmt.Errorf("arrays must be on a single line")
+			}
+			value, after, err := parseTOMLString(rest)
+			if err != nil {
+				return nil, "", fmt.Errorf("arrays may only contain strings")
+			}
+			values = append(values, value)
+			rest = strings.TrimSpace(after)
+			if strings.HasPrefix(rest, ",") {
+				rest = strings.TrimSpace(rest[1:])
+			} else if !strings.HasPrefix(rest, "]") {
+				return nil, "", fmt.Errorf("unterminated array")
+			}
+		}
+		return values, rest[1:], nil
+	}
+
+	token, rest, _ := strings.Cut(s, " ")
+	token, comment, found := strings.Cut(token, "#")
+	if found {
+		rest = "#" + comment + " " + rest
+	}
+
+	switch token {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	if n, err := strconv.ParseInt(strings.ReplaceAll(token, "_", ""), 10, 64); err == nil {
+		return n, rest, nil
+	}
+	return nil, "", fmt.Errorf("invalid value %q", token)
+}
+
+// parseTOMLString parses a basic ("...") or literal ('...') string at the
+// start of s.
+func parseTOMLString(s string) (string, string, error) {
+	if strings.HasPrefix(s, "'") {
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", fmt.Errorf("expected string")
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return value, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+func isTOMLComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || strings.HasPrefix(s, "#")
+}
+
+// expandHome replaces a leading ~ in path with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
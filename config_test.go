@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	input := `# lav config
+projects = ["~/src/game", '/srv/app'] # trailing comment
+
+[apps.godot]
+keep = 3
+older_than = "90d"
+pinned = ["4.2.2"]
+enabled = true
+`
+	tables, err := parseTOML(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	projects, _ := tables[""]["projects"].([]string)
+	if strings.Join(projects, ",") != "~/src/game,/srv/app" {
+		t.Errorf("unexpected projects: %v", tables[""]["projects"])
+	}
+
+	godot := tables["apps.godot"]
+	if godot["keep"] != int64(3) {
+		t.Errorf("expected keep=3, got %v", godot["keep"])
+	}
+	if godot["older_than"] != "90d" {
+		t.Errorf("expected older_than=90d, got %v", godot["older_than"])
+	}
+	if godot["enabled"] != true {
+		t.Errorf("expected enabled=true, got %v", godot["enabled"])
+	}
+}
+
+func TestParseTOML_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"key",
+		"key = bare",
+		`key = "unterminated`,
+		"key = [1, 2]",
+		`key = "a" "b"`,
+		"[unterminated",
+	} {
+		if _, err := parseTOML(strings.NewReader(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestParseTOML_Unsupported(t *testing.T) {
+	tests := map[string]string{
+		"[[apps]]\nkeep = 1":                    "line 1: arrays of tables",
+		"# config\napps.godot.keep = 3":         "line 2: dotted key",
+		"[]":                                    "line 1: missing key",
+		"[apps.go]\n[apps.go]":                  "line 2: table [apps.go] is defined twice",
+		"keep = 1\nkeep = 2":                    "line 2: keep is defined twice",
+		"bad key = 1":                           "line 1: invalid key",
+		"godot = { keep = 3 }":                  "line 1: inline tables",
+		"pinned = [\n  \"4.2.2\",\n]":           "line 1: arrays must be on a single line",
+		`notes = """text"""`:                    "line 1: multi-line strings",
+		"ratio = 1.5":                           "line 1: invalid value",
+		"when = 2024-01-01":                     "line 1: invalid value",
+		"[apps.\"my.app\"]\nkeep = 1\n[apps.x]": "",
+	}
+	for input, want := range tests {
+		_, err := parseTOML(strings.NewReader(input))
+		switch {
+		case want == "" && err != nil:
+			t.Errorf("unexpected error for %q: %v", input, err)
+		case want != "" && (err == nil || !strings.HasPrefix(err.Error(), want)):
+			t.Errorf("expected %q for %q, got %v", want, input, err)
+		}
+	}
+
+	tables, _ := parseTOML(strings.NewReader("[apps.\"my.app\"]\nkeep = 1"))
+	if tables["apps.my.app"]["keep"] != int64(1) {
+		t.Errorf("expected the quoted table name to be unquoted, got %v", tables)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	t.Setenv("LAV_CONFIG", path)
+
+	// A missing config file is not an error
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Apps) != 0 {
+		t.Errorf("expected empty config, got %v", cfg.Apps)
+	}
+
+	os.WriteFile(path, []byte("[apps.go]\nkeep = 2\npinned = \"1.21.0\"\n"), 0644)
+	cfg, err = loadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Apps["go"].Keep != 2 || len(cfg.Apps["go"].Pinned) != 1 {
+		t.Errorf("unexpected app config: %+v", cfg.Apps["go"])
+	}
+
+	os.WriteFile(path, []byte("bin_dir = \"/opt/team/bin\"\n"), 0644)
+	cfg, err = loadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.BinDirs) != 1 || cfg.BinDirs[0] != "/opt/team/bin" {
+		t.Errorf("unexpected bin_dir: %v", cfg.BinDirs)
+	}
+
+	os.WriteFile(path, []byte("[apps.go]\nkeep = \"two\"\n"), 0644)
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected error for invalid keep")
+	}
+
+	os.WriteFile(path, []byte("[apps.go.env]\nGOROOT = \"{prefix}\"\n\n[apps.go]\nkeep = 2\n"), 0644)
+	cfg, err = loadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Apps["go"].Env["GOROOT"] != "{prefix}" || cfg.Apps["go"].Keep != 2 {
+		t.Errorf("unexpected app config: %+v", cfg.Apps["go"])
+	}
+
+	os.WriteFile(path, []byte("[apps.go]\nmode = \"copy\"\n"), 0644)
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// versionClause is a single comparison in a version constraint, such as
+// ">=1.21" or the prefix match "1.23".
+type versionClause struct {
+	op      string // "=", "!=", ">", ">=", "<", "<=" or "prefix"
+	version parsedVersion
+}
+
+func (c versionClause) matches(v parsedVersion) bool {
+	if c.op == "prefix" {
+		if len(v.release) < len(c.version.release) {
+			return false
+		}
+		for i, n := range c.version.release {
+			if v.release[i] != n {
+				return false
+			}
+		}
+		return true
+	}
+
+	cmp := compareParsedVersions(v, c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// parseConstraint parses a version constraint into clauses that must all
+// match, and reports whether any version in it is a pre-release. Supported
+// forms are partial versions ("1.23" matches any 1.23.x), caret ranges
+// ("^4.5" is >=4.5.0 <5.0.0), tilde ranges ("~1.22.3" is >=1.22.3 <1.23.0)
+// and comparisons joined by commas or spaces (">=1.21, <1.23").
+func parseConstraint(spec string) ([]versionClause, bool, error) {
+	var clauses []versionClause
+	hasPre := false
+
+	fields := strings.Fields(strings.ReplaceAll(spec, ",", " "))
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+
+		op := ""
+		for _, prefix := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(field, prefix) {
+				op = prefix
+				break
+			}
+		}
+		rest := strings.TrimPrefix(field, op)
+		// Allow a space between the operator and the version: ">= 1.21"
+		if rest == "" && op != "" && i+1 < len(fields) {
+			i++
+			rest = fields[i]
+		}
+
+		v, ok := parseVersion(rest)
+		if !ok {
+			return nil, false, fmt.Errorf("invalid version constraint: %q", spec)
+		}
+		if len(v.pre) > 0 {
+			hasPre = true
+		}
+
+		switch op {
+		case "":
+			if len(v.pre) > 0 {
+				clauses = append(clauses, versionClause{op: "=", version: v})
+			} else {
+				clauses = append(clauses, versionClause{op: "prefix", version: v})
+			}
+		case "^":
+			clauses = append(clauses,
+				versionClause{op: ">=", version: v},
+				versionClause{op: "<", version: caretUpperBound(v)})
+		case "~":
+			clauses = append(clauses,
+				versionClause{op: ">=", version: v},
+				versionClause{op: "<", version: tildeUpperBound(v)})
+		default:
+			clauses = append(clauses, versionClause{op: op, version: v})
+		}
+	}
+
+	if len(clauses) == 0 {
+		return nil, false, fmt.Errorf("empty version constraint")
+	}
+	return clauses, hasPre, nil
+}
+
+// caretUpperBound returns the exclusive upper bound of ^v: the next version
+// that changes the leftmost non-zero component.
+func caretUpperBound(v parsedVersion) parsedVersion {
+	for i, n := range v.release {
+		if n != 0 || i == len(v.release)-1 {
+			return bumpVersion(v, i)
+		}
+	}
+	return bumpVersion(v, 0)
+}
+
+// tildeUpperBound returns the exclusive upper bound of ~v: the next minor
+// version, or the next major version if only a major version was given.
+func tildeUpperBound(v parsedVersion) parsedVersion {
+	if len(v.release) == 1 {
+		return bumpVersion(v, 0)
+	}
+	return bumpVersion(v, 1)
+}
+
+// bumpVersion increments release component i and drops everything after
+// it. The result carries the lowest possible pre-release so that, as an
+// exclusive upper bound, it also excludes pre-releases of the bumped version.
+func bumpVersion(v parsedVersion, i int) parsedVersion {
+	release := append([]int{}, v.release[:i+1]...)
+	release[i]++
+	return parsedVersion{release: release, pre: []string{"0"}}
+}
+
+// resolveVersion returns the installed version of app that spec selects.
+// spec is an exact version name, "latest", or a constraint accepted by
+// parseConstraint; the highest matching version wins. Pre-releases are only
+// considered when pre is set or the constraint itself names a pre-release.
+func resolveVersion(app string, versions []string, spec string, pre bool) (string, error) {
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no versions installed for %s", app)
+	}
+
+	for _, v := range versions {
+		if v == spec {
+			return v, nil
+		}
+	}
+
+	var clauses []versionClause
+	if spec != "latest" {
+		var hasPre bool
+		var err error
+		if clauses, hasPre, err = parseConstraint(spec); err != nil {
+			return "", err
+		}
+		pre = pre || hasPre
+	}
+
+	// versions is sorted oldest first
+	for i := len(versions) - 1; i >= 0; i-- {
+		v, ok := parseVersion(versions[i])
+		if !ok || (len(v.pre) > 0 && !pre) {
+			continue
+		}
+
+		matched := true
+		for _, c := range clauses {
+			if !c.matches(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return versions[i], nil
+		}
+	}
+
+	return "", fmt.Errorf("no installed version of %s matches %q (installed: %s)", app, spec, strings.Join(versions, ", "))
+}
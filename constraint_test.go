@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestResolveVersion(t *testing.T) {
+	versions := []string{"1.21.5", "1.22.0", "1.22.3", "1.22.7", "1.23.0", "1.23.4", "1.24rc1", "nightly"}
+	sortVersions(versions)
+
+	tests := []struct {
+		spec     string
+		pre      bool
+		expected string
+	}{
+		{"1.22.3", false, "1.22.3"},
+		{"nightly", false, "nightly"},
+		{"latest", false, "1.23.4"},
+		{"latest", true, "1.24rc1"},
+		{"1.22", false, "1.22.7"},
+		{"1", false, "1.23.4"},
+		{"^1.22", false, "1.23.4"},
+		{"~1.22.3", false, "1.22.7"},
+		{"~1.22", false, "1.22.7"},
+		{">=1.21, <1.23", false, "1.22.7"},
+		{">= 1.21 < 1.22", false, "1.21.5"},
+		{"<=1.22.0", false, "1.22.0"},
+		{"!=1.23.4", false, "1.23.0"},
+		{"=1.22.0", false, "1.22.0"},
+		{">1.23", false, "1.23.4"},
+		{">1.23", true, "1.24rc1"},
+		{"1.24rc1", false, "1.24rc1"},
+		{">=1.24rc1", false, "1.24rc1"},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveVersion("go", versions, tt.spec, tt.pre)
+		if err != nil {
+			t.Errorf("resolveVersion(%q, pre=%v): unexpected error: %v", tt.spec, tt.pre, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("resolveVersion(%q, pre=%v) = %s, want %s", tt.spec, tt.pre, got, tt.expected)
+		}
+	}
+}
+
+func TestResolveVersion_CaretZeroMajor(t *testing.T) {
+	versions := []string{"0.3.1", "0.3.9", "0.4.0", "0.0.3", "0.0.4"}
+	sortVersions(versions)
+
+	tests := map[string]string{
+		"^0.3":   "0.3.9",
+		"^0.0.3": "0.0.3",
+	}
+	for spec, want := range tests {
+		got, err := resolveVersion("app", versions, spec, false)
+		if err != nil || got != want {
+			t.Errorf("resolveVersion(%q) = %s, %v; want %s", spec, got, err, want)
+		}
+	}
+}
+
+func TestResolveVersion_UpperBoundExcludesPreRelease(t *testing.T) {
+	versions := []string{"4.5.1", "5.0.0-beta1"}
+	got, err := resolveVersion("godot", versions, "^4.5", true)
+	if err != nil || got != "4.5.1" {
+		t.Errorf("expected 4.5.1, got %s (%v)", got, err)
+	}
+}
+
+func TestResolveVersion_NoMatch(t *testing.T) {
+	versions := []string{"1.22.0", "1.23.0"}
+
+	if _, err := resolveVersion("go", versions, "1.24", false); err == nil {
+		t.Error("expected error when nothing matches")
+	}
+	if _, err := resolveVersion("go", versions, "not a version", false); err == nil {
+		t.Error("expected error for invalid constraint")
+	}
+	if _, err := resolveVersion("go", nil, "latest", false); err == nil {
+		t.Error("expected error without installed versions")
+	}
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// binDirsNotOnPath returns the directories in binDirs that are missing from
+// pathEnv, a PATH-style list. Entries are compared after resolving symlinks.
+func binDirsNotOnPath(binDirs []string, pathEnv string) []string {
+	onPath := make(map[string]bool)
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			continue
+		}
+		onPath[filepath.Clean(dir)] = true
+		onPath[realPath(dir)] = true
+	}
+
+	var missing []string
+	for _, dir := range binDirs {
+		if !onPath[filepath.Clean(dir)] && !onPath[realPath(dir)] {
+			missing = append(missing, dir)
+		}
+	}
+	return missing
+}
+
+// Severities of doctor findings. Errors break the apps involved; warnings
+// are likely to cause surprises; notes are harmless leftovers.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityNote    = "note"
+)
+
+// doctorFinding is a problem found by lav doctor. Problems lav can repair
+// carry a description of the fix and the function that applies it.
+type doctorFinding struct {
+	severity string
+	message  string
+	fix      string
+	apply    func() error
+}
+
+// doctorFindings inspects the lav tree at baseDir and the bin directories.
+// Findings are ordered so that applying their fixes in turn works: current
+// versions are repaired before the bin links that point through them.
+func doctorFindings(baseDir string) ([]doctorFinding, error) {
+	apps, err := listApps(baseDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var findings []doctorFinding
+	for _, app := range apps {
+		appFindings, err := doctorAppFindings(baseDir, app)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, appFindings...)
+	}
+
+	linkFindings, err := doctorLinkFindings(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, linkFindings...)
+
+	binDirs, err := getBinDirs()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range binDirsNotOnPath(binDirs, os.Getenv("PATH")) {
+		findings = append(findings, doctorFinding{
+			severity: severityWarning,
+			message:  "bin directory " + dir + " is not on PATH; installed executables will not be found",
+		})
+	}
+
+	stale, err := staleStagingDirs(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range stale {
+		findings = append(findings, doctorFinding{
+			severity: severityNote,
+			message:  "leftover staging directory " + dir + " from an interrupted install",
+			fix:      "remove it",
+			apply:    func() error { return os.RemoveAll(dir) },
+		})
+	}
+	return findings, nil
+}
+
+// doctorAppFindings inspects the current entry and the versions of app.
+func doctorAppFindings(baseDir, app string) ([]doctorFinding, error) {
+	versions, err := listVersions(baseDir, app)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []doctorFinding
+	currentLink := filepath.Join(baseDir, app, "current")
+	if info, err := os.Lstat(currentLink); err == nil {
+		switch {
+		case info.IsDir():
+			// lav never replaces a current entry that is not a symlink, so
+			// the app is stuck on whatever the directory holds
+			recovered := "recovered-" + time.Now().Format("20060102150405")
+			findings = append(findings, doctorFinding{
+				severity: severityError,
+				message:  currentLink + " is a directory, not a symlink; lav use cannot switch " + app,
+				fix:      "keep it as version " + recovered + " and make that current",
+				apply: func() error {
+					if err := os.Rename(currentLink, filepath.Join(baseDir, app, recovered)); err != nil {
+						return err
+					}
+					return activateVersion(baseDir, app, recovered, false)
+				},
+			})
+		case info.Mode()&os.ModeSymlink == 0:
+			finding := doctorFinding{
+				severity: severityError,
+				message:  currentLink + " is a file, not a symlink; lav use cannot switch " + app,
+			}
+			if len(versions) > 0 {
+				latest := versions[len(versions)-1]
+				finding.fix = "move it to current.bak and switch to " + latest
+				finding.apply = func() error {
+					if err := os.Rename(currentLink, currentLink+".bak"); err != nil {
+						return err
+					}
+					return activateVersion(baseDir, app, latest, false)
+				}
+			}
+			findings = append(findings, finding)
+		default:
+			current, _ := getCurrentVersion(baseDir, app)
+			if !slices.Contains(versions, current) {
+				findings = append(findings, danglingCurrentFinding(baseDir, app, current, versions))
+			}
+		}
+	}
+
+	for _, version := range versions {
+		binDir := filepath.Join(baseDir, app, version, "bin")
+		entries, err := os.ReadDir(binDir)
+		if os.IsNotExist(err) {
+			findings = append(findings, doctorFinding{
+				severity: severityWarning,
+				message:  fmt.Sprintf("%s %s has no bin/ directory; it provides no executables", app, version),
+			})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			path := filepath.Join(binDir, entry.Name())
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() || info.Mode()&0111 != 0 {
+				continue
+			}
+			findings = append(findings, doctorFinding{
+				severity: severityNote,
+				message:  path + " is not executable and is not linked",
+			})
+		}
+	}
+	return findings, nil
+}
+
+// danglingCurrentFinding reports a current symlink of app pointing at a
+// version that is not installed. The fix switches to the latest installed
+// version, or removes the link if there is none.
+func danglingCurrentFinding(baseDir, app, current string, versions []string) doctorFinding {
+	finding := doctorFinding{
+		severity: severityError,
+		message:  fmt.Sprintf("current version %s of %s is not installed", current, app),
+	}
+	if len(versions) > 0 {
+		latest := versions[len(versions)-1]
+		finding.fix = "switch to " + latest
+		finding.apply = func() error { return activateVersion(baseDir, app, latest, false) }
+	} else {
+		finding.fix = "remove the current symlink and the links of " + app
+		finding.apply = func() error {
+			if err := os.Remove(filepath.Join(baseDir, app, "current")); err != nil {
+				return err
+			}
+			_, err := syncBinLinks(baseDir, app, false)
+			return err
+		}
+	}
+	return finding
+}
+
+// doctorLinkFindings inspects the bin links of apps: those recorded in the
+// link registry, and those in the bin directories that point into the lav
+// root, or at an installed app's current version under another root, but
+// were created before the registry existed or dropped from it. It reports
+// links that were removed, point outside the lav root or no longer
+// resolve.
+func doctorLinkFindings(baseDir string) ([]doctorFinding, error) {
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	links, err := unregisteredLinks(baseDir, reg)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(links, reg.Links)
+
+	var findings []doctorFinding
+	for _, link := range slices.Sorted(maps.Keys(links)) {
+		app := links[link]
+		info, err := os.Lstat(link)
+		switch {
+		case os.IsNotExist(err):
+			findings = append(findings, doctorFinding{
+				severity: severityNote,
+				message:  "link registry lists " + link + " of " + app + ", which no longer exists",
+				fix:      "forget it",
+				apply:    func() error { return forgetLink(baseDir, link) },
+			})
+			continue
+		case err != nil:
+			return nil, err
+		case info.Mode()&os.ModeSymlink == 0:
+			findings = append(findings, doctorFinding{
+				severity: severityWarning,
+				message:  link + " of " + app + " has been replaced by a file lav does not manage",
+				fix:      "forget it",
+				apply:    func() error { return forgetLink(baseDir, link) },
+			})
+			continue
+		}
+
+		if isShimLink(link) {
+			continue
+		}
+		if !linkPointsInto(link, filepath.Join(baseDir, app)) {
+			target, _ := os.Readlink(link)
+			findings = append(findings, doctorFinding{
+				severity: severityWarning,
+				message:  fmt.Sprintf("%s of %s points outside the lav root, at %s", link, app, target),
+				fix:      "relink " + app,
+				apply:    func() error { return relinkLink(baseDir, app, link) },
+			})
+			continue
+		}
+		if _, err := os.Stat(link); err != nil {
+			findings = append(findings, doctorFinding{
+				severity: severityError,
+				message:  link + " of " + app + " is dangling",
+				fix:      "relink " + app,
+				apply:    func() error { return relinkLink(baseDir, app, link) },
+			})
+		}
+	}
+	return findings, nil
+}
+
+// unregisteredLinks returns the symlinks in the bin directories that the
+// link registry does not list but that belong to an app, keyed by link.
+func unregisteredLinks(baseDir string, reg *linkRegistry) (map[string]string, error) {
+	binDirs, err := absBinDirs()
+	if err != nil {
+		return nil, err
+	}
+	apps, err := listApps(baseDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	links := make(map[string]string)
+	for _, binDir := range binDirs {
+		entries, err := os.ReadDir(binDir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			link := filepath.Join(binDir, entry.Name())
+			if _, ok := reg.Links[link]; ok || entry.Type()&os.ModeSymlink == 0 {
+				continue
+			}
+			if app := reg.owner(baseDir, link); app != "" {
+				links[link] = app
+			} else if app := foreignRootApp(link, apps); app != "" {
+				links[link] = app
+			}
+		}
+	}
+	return links, nil
+}
+
+// foreignRootApp returns the app in apps whose current version link points
+// at under some other lav root, as links do after the root moved, or "".
+func foreignRootApp(link string, apps []string) string {
+	target, err := os.Readlink(link)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(target)), "/")
+	n := len(parts)
+	if n < 4 || parts[n-1] != filepath.Base(link) || parts[n-2] != "bin" || parts[n-3] != "current" {
+		return ""
+	}
+	if app := parts[n-4]; slices.Contains(apps, app) {
+		return app
+	}
+	return ""
+}
+
+// relinkLink rewrites the bin links of app, the owner of link. If app no
+// longer has a current version, link is removed instead. Nothing is done if
+// an earlier fix gave link to another app. A link missing from the registry
+// is recorded as app's first, so that it can be rewritten.
+func relinkLink(baseDir, app, link string) error {
+	owned := true
+	err := updateLinkRegistry(baseDir, func(reg *linkRegistry) error {
+		if owner, ok := reg.Links[link]; ok && owner != app {
+			owned = false
+		} else if !ok {
+			reg.Links[link] = app
+		}
+		return nil
+	})
+	if err != nil || !owned {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(baseDir, app, "current")); err != nil {
+		if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return forgetLink(baseDir, link)
+	}
+	_, err = syncBinLinks(baseDir, app, false)
+	return err
+}
+
+// forgetLink drops link from the link registry.
+func forgetLink(baseDir, link string) error {
+	return updateLinkRegistry(baseDir, func(reg *linkRegistry) error {
+		delete(reg.Links, link)
+		return nil
+	})
+}
+
+// runDoctor inspects the lav tree and, with fix, applies the fixes of the
+// findings in order. It returns the findings with the outcome of each fix.
+func runDoctor(baseDir string, fix bool) ([]findingJSON, error) {
+	findings, err := doctorFindings(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []findingJSON{}
+	for _, f := range findings {
+		result := findingJSON{Severity: f.severity, Message: f.message, Fix: f.fix}
+		if fix && f.apply != nil {
+			if err := f.apply(); err != nil {
+				result.FixError = err.Error()
+			} else {
+				result.Fixed = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
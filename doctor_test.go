@@ -0,0 +1,225 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBinDirsNotOnPath(t *testing.T) {
+	dir := t.TempDir()
+	onPath := filepath.Join(dir, "bin")
+	missing := filepath.Join(dir, "other")
+	os.MkdirAll(onPath, 0755)
+
+	// A symlinked PATH entry still counts
+	alias := filepath.Join(dir, "alias")
+	os.Symlink(onPath, alias)
+
+	pathEnv := strings.Join([]string{"/usr/bin", alias + "/"}, string(os.PathListSeparator))
+	got := binDirsNotOnPath([]string{onPath, missing}, pathEnv)
+	if len(got) != 1 || got[0] != missing {
+		t.Errorf("expected [%s], got %v", missing, got)
+	}
+}
+
+// testDoctorEnv sets up HOME with its bin directory on PATH, so that lav
+// doctor only reports problems of the tree.
+func testDoctorEnv(t *testing.T) string {
+	t.Helper()
+	home := setupTestEnv(t)
+	t.Setenv("PATH", filepath.Join(home, ".local", "bin"))
+	return home
+}
+
+// applyFindings applies the fixes of findings and fails the test if one
+// has none or fails.
+func applyFindings(t *testing.T, findings []doctorFinding) {
+	t.Helper()
+	for _, f := range findings {
+		if f.apply == nil {
+			t.Fatalf("expected a fix for %q", f.message)
+		}
+		if err := f.apply(); err != nil {
+			t.Fatalf("fix of %q failed: %v", f.message, err)
+		}
+	}
+}
+
+func TestDoctorFindings_Clean(t *testing.T) {
+	testDoctorEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+
+	findings, err := doctorFindings(baseDir)
+	if err != nil || len(findings) != 0 {
+		t.Errorf("expected no findings, got %v %v", findings, err)
+	}
+}
+
+func TestDoctorFindings_CurrentDirectory(t *testing.T) {
+	home := testDoctorEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+
+	// A copied tree can leave current as a real directory
+	current := filepath.Join(baseDir, "go", "current")
+	os.Remove(current)
+	if err := copyDir(filepath.Join(baseDir, "go", "1.22.0"), current); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := doctorFindings(baseDir)
+	if err != nil || len(findings) != 1 || findings[0].severity != severityError {
+		t.Fatalf("expected one error, got %v %v", findings, err)
+	}
+	applyFindings(t, findings)
+
+	version, err := getCurrentVersion(baseDir, "go")
+	if err != nil || !strings.HasPrefix(version, "recovered-") {
+		t.Errorf("expected a recovered version, got %q %v", version, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(home, ".local", "bin", "go")); err != nil || string(data) != "go 1.22.0" {
+		t.Errorf("expected the bin link to resolve, got %q %v", data, err)
+	}
+	if findings, _ := doctorFindings(baseDir); len(findings) != 0 {
+		t.Errorf("expected no findings after fixing, got %v", findings)
+	}
+}
+
+func TestDoctorFindings_DanglingLinks(t *testing.T) {
+	home := testDoctorEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.0", "go")
+	os.RemoveAll(filepath.Join(baseDir, "go", "1.23.0"))
+
+	// Both current and the bin link dangle; fixing current repairs the link
+	findings, err := doctorFindings(baseDir)
+	if err != nil || len(findings) != 2 {
+		t.Fatalf("expected two findings, got %v %v", findings, err)
+	}
+	if !strings.Contains(findings[0].message, "current version 1.23.0") || !strings.Contains(findings[1].message, "dangling") {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+	applyFindings(t, findings)
+
+	if version, _ := getCurrentVersion(baseDir, "go"); version != "1.22.0" {
+		t.Errorf("expected current=1.22.0, got %s", version)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".local", "bin", "go")); err != nil {
+		t.Errorf("expected the bin link to resolve: %v", err)
+	}
+}
+
+func TestDoctorFindings_WrongRoot(t *testing.T) {
+	home := testDoctorEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+
+	// A copy of the root that the links still point at
+	oldRoot := filepath.Join(t.TempDir(), "lav")
+	os.MkdirAll(filepath.Join(oldRoot, "go", "1.22.0", "bin"), 0755)
+	os.WriteFile(filepath.Join(oldRoot, "go", "1.22.0", "bin", "go"), []byte("go 1.22.0"), 0755)
+	link := filepath.Join(home, ".local", "bin", "go")
+	os.Remove(link)
+	os.Symlink(filepath.Join(oldRoot, "go", "1.22.0", "bin", "go"), link)
+
+	findings, err := doctorFindings(baseDir)
+	if err != nil || len(findings) != 1 || !strings.Contains(findings[0].message, "outside the lav root") {
+		t.Fatalf("expected a link outside the root, got %v %v", findings, err)
+	}
+	applyFindings(t, findings)
+	if !linkPointsInto(link, baseDir) {
+		t.Error("expected the link to point into the lav root")
+	}
+}
+
+func TestDoctorFindings_Leftovers(t *testing.T) {
+	testDoctorEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	os.WriteFile(filepath.Join(baseDir, "go", "1.22.0", "bin", "README"), []byte("docs"), 0644)
+	os.MkdirAll(filepath.Join(baseDir, "go", "1.23.0"), 0755)
+	stale := filepath.Join(stagingDir(baseDir), "999999999-go-1.24.0-123")
+	os.MkdirAll(stale, 0755)
+
+	findings, err := doctorFindings(baseDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var severities []string
+	for _, f := range findings {
+		severities = append(severities, f.severity)
+	}
+	// README is not executable, 1.23.0 has no bin/, and staging is stale
+	if strings.Join(severities, " ") != "note warning note" {
+		t.Fatalf("unexpected findings: %v", findings)
+	}
+
+	results, err := runDoctor(baseDir, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !results[2].Fixed || results[0].Fixed || results[0].Fix != "" {
+		t.Errorf("only the staging directory can be fixed: %+v", results)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("expected the staging directory to be removed")
+	}
+}
+
+func TestDoctorFindings_UnregisteredLinks(t *testing.T) {
+	home := testDoctorEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	binDir := filepath.Join(home, ".local", "bin")
+
+	// Links from before the registry: one into the root that dangles, one
+	// into an old root
+	os.Symlink(filepath.Join(baseDir, "go", "current", "bin", "gone"), filepath.Join(binDir, "gone"))
+	os.Remove(filepath.Join(binDir, "go"))
+	os.Symlink(filepath.Join(t.TempDir(), "lav", "go", "current", "bin", "go"), filepath.Join(binDir, "go"))
+	if err := forgetLink(baseDir, filepath.Join(binDir, "go")); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := doctorFindings(baseDir)
+	if err != nil || len(findings) != 2 {
+		t.Fatalf("expected two findings, got %v %v", findings, err)
+	}
+	if !strings.Contains(findings[0].message, "go of go points outside the lav root") || !strings.Contains(findings[1].message, "gone of go is dangling") {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+	applyFindings(t, findings)
+
+	if data, err := os.ReadFile(filepath.Join(binDir, "go")); err != nil || string(data) != "go 1.22.0" {
+		t.Errorf("expected the link to be relinked, got %q %v", data, err)
+	}
+	if _, err := os.Lstat(filepath.Join(binDir, "gone")); !os.IsNotExist(err) {
+		t.Error("expected the dangling link to be removed")
+	}
+}
+
+func TestDoctorFindings_CurrentFile(t *testing.T) {
+	testDoctorEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	current := filepath.Join(baseDir, "go", "current")
+	os.Remove(current)
+	os.WriteFile(current, []byte("1.22.0\n"), 0644)
+
+	findings, err := doctorFindings(baseDir)
+	// The bin link through current dangles as well
+	if err != nil || len(findings) != 2 || !strings.Contains(findings[0].message, "is a file, not a symlink") {
+		t.Fatalf("unexpected findings: %v %v", findings, err)
+	}
+	applyFindings(t, findings)
+	if version, _ := getCurrentVersion(baseDir, "go"); version != "1.22.0" {
+		t.Errorf("expected current=1.22.0, got %s", version)
+	}
+	if _, err := os.Stat(current + ".bak"); err != nil {
+		t.Errorf("expected the file to be kept as current.bak: %v", err)
+	}
+}
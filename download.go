@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// cacheEntry records which cached artifact a URL resolved to.
+type cacheEntry struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+	Name   string `json:"name"`
+}
+
+// httpClient fetches artifacts, checksums and signatures. Connecting and
+// waiting for response headers time out; bodies may take as long as they
+// need as long as data keeps arriving (see downloadStallTimeout).
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   30 * time.Second,
+		ResponseHeaderTimeout: 60 * time.Second,
+	},
+}
+
+// downloadStallTimeout is how long a response body may go without
+// delivering any data before the download is abandoned.
+var downloadStallTimeout = 60 * time.Second
+
+// stallReader reads from a response body and cancels its request when no
+// data arrives for downloadStallTimeout.
+type stallReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	stalled *atomic.Bool
+}
+
+func (s stallReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.timer.Reset(downloadStallTimeout)
+	}
+	if err != nil && s.stalled.Load() {
+		err = fmt.Errorf("no data received for %s", downloadStallTimeout)
+	}
+	return n, err
+}
+
+// httpGet sends req with httpClient and returns the response with a body
+// that fails once it stalls. The returned stop function must be called when
+// the body is no longer read.
+func httpGet(req *http.Request) (*http.Response, func(), error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	stalled := new(atomic.Bool)
+	timer := time.AfterFunc(downloadStallTimeout, func() {
+		stalled.Store(true)
+		cancel()
+	})
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{stallReader{r: resp.Body, timer: timer, stalled: stalled}, resp.Body}
+	return resp, func() {
+		timer.Stop()
+		cancel()
+	}, nil
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// cacheDir returns the download cache directory. Artifacts are stored by
+// content as sha256/<hex>/<name>, with urls/ mapping each URL to its hash and
+// partial/ holding interrupted downloads along with the files that lock
+// them.
+func cacheDir(baseDir string) string {
+	return filepath.Join(baseDir, ".cache")
+}
+
+func cachedArtifactPath(baseDir, sum, name string) string {
+	return filepath.Join(cacheDir(baseDir), "sha256", sum, name)
+}
+
+// downloadArtifact returns the local path of the artifact at rawURL. A
+// previously downloaded artifact is reused from the cache, looked up by its
+// expected sha256 when known and by URL otherwise; if neither is cached it is
+// fetched, resuming any partial download left by an earlier attempt.
+func downloadArtifact(baseDir, rawURL, expected string) (string, error) {
+	name, err := artifactName(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if expected != "" {
+		cached := cachedArtifactPath(baseDir, expected, name)
+		if sum, err := fileSHA256(cached); err == nil && sum == expected {
+			return cached, nil
+		}
+	}
+
+	key := sha256Hex([]byte(rawURL))
+	entryPath := filepath.Join(cacheDir(baseDir), "urls", key+".json")
+
+	if cached, ok := cachedURLArtifact(baseDir, entryPath); ok {
+		return cached, nil
+	} else if cached != "" {
+		// Missing or corrupted blob; fall through and download again
+		os.RemoveAll(filepath.Dir(cached))
+	}
+
+	partialDir := filepath.Join(cacheDir(baseDir), "partial")
+	if err := os.MkdirAll(partialDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Concurrent downloads of the same URL would write to the same partial
+	// file; the second one waits and then uses what the first stored
+	partial := filepath.Join(partialDir, key)
+	unlock, err := lockPath(partial + ".lock")
+	if err != nil {
+		return "", fmt.Errorf("failed to lock partial download: %w", err)
+	}
+	defer unlock()
+	if cached, ok := cachedURLArtifact(baseDir, entryPath); ok {
+		return cached, nil
+	}
+
+	if err := fetchURL(rawURL, partial); err != nil {
+		return "", err
+	}
+
+	sum, err := fileSHA256(partial)
+	if err != nil {
+		return "", err
+	}
+
+	cached := cachedArtifactPath(baseDir, sum, name)
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.Rename(partial, cached); err != nil {
+		return "", fmt.Errorf("failed to store download: %w", err)
+	}
+	os.Remove(validatorPath(partial))
+
+	entry := cacheEntry{URL: rawURL, SHA256: sum, Name: name}
+	if err := writeCacheEntry(entryPath, entry); err != nil {
+		return "", err
+	}
+
+	return cached, nil
+}
+
+// cachedURLArtifact returns the artifact the cache entry at entryPath maps
+// its URL to, and whether it is present and intact. The path is "" when
+// there is no entry.
+func cachedURLArtifact(baseDir, entryPath string) (string, bool) {
+	entry, err := readCacheEntry(entryPath)
+	if err != nil {
+		return "", false
+	}
+	cached := cachedArtifactPath(baseDir, entry.SHA256, entry.Name)
+	sum, err := fileSHA256(cached)
+	return cached, err == nil && sum == entry.SHA256
+}
+
+// fetchURL downloads rawURL into dst. If dst already holds a partial
+// download, only the remaining bytes are requested, with If-Range set to
+// the ETag or Last-Modified date recorded when the download started; a file
+// that changed upstream is then downloaded again from the start instead of
+// being spliced onto the old one.
+func fetchURL(rawURL, dst string) error {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	validator, err := os.ReadFile(validatorPath(dst))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Without a validator there is no telling what the partial download is
+	// part of
+	restart := offset > 0 && len(validator) == 0
+	if !restart {
+		if restart, err = fetchRange(rawURL, f, dst, offset, string(validator)); err != nil {
+			return err
+		}
+	}
+	if restart {
+		if err := truncateFile(f); err != nil {
+			return err
+		}
+		again, err := fetchRange(rawURL, f, dst, 0, "")
+		if err != nil {
+			return err
+		}
+		if again {
+			return fmt.Errorf("failed to download %s: unexpected Content-Range", rawURL)
+		}
+	}
+
+	return f.Close()
+}
+
+// fetchRange appends rawURL to f, which holds the first offset bytes of it,
+// and records the validator of a download that starts over in
+// validatorPath(dst). It reports whether the server answered with a range
+// other than the one requested, in which case nothing was written.
+func fetchRange(rawURL string, f *os.File, dst string, offset int64, validator string) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	}
+
+	resp, stop, err := httpGet(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer stop()
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// Server honored the range; append to what we have
+		if start, _, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || start != offset {
+			return true, nil
+		}
+	case http.StatusOK:
+		// Server ignored the range, there was none, or the file changed;
+		// start over
+		if err := truncateFile(f); err != nil {
+			return false, err
+		}
+		if err := writeValidator(dst, resp.Header); err != nil {
+			return false, err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial download is already complete if it has the size of
+		// the file
+		if offset > 0 {
+			_, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+			return !ok || size != offset, nil
+		}
+		fallthrough
+	default:
+		return false, fmt.Errorf("failed to download %s: %s", rawURL, resp.Status)
+	}
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return false, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	return false, nil
+}
+
+// validatorPath returns the file that records the ETag or Last-Modified
+// date of the partial download dst.
+func validatorPath(dst string) string {
+	return dst + ".validator"
+}
+
+// writeValidator records the validator of a download starting at byte 0:
+// its strong ETag, or else its Last-Modified date. Without either, a later
+// attempt cannot resume it.
+func writeValidator(dst string, header http.Header) error {
+	validator := header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = header.Get("Last-Modified")
+	}
+	if validator == "" {
+		if err := os.Remove(validatorPath(dst)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(validatorPath(dst), []byte(validator), 0644)
+}
+
+// parseContentRange parses a Content-Range header such as
+// "bytes 400-999/1000" or "bytes */1000". It returns the first byte of the
+// range, or -1 for "*", and the size of the file, or -1 if it is unknown.
+func parseContentRange(value string) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rng, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, false
+	}
+
+	start, total := int64(-1), int64(-1)
+	var err error
+	if rng != "*" {
+		first, _, ok := strings.Cut(rng, "-")
+		if !ok {
+			return 0, 0, false
+		}
+		if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, total, true
+}
+
+// truncateFile empties f and moves its offset back to the start.
+func truncateFile(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.Seek(0, io.SeekStart)
+	return err
+}
+
+// artifactName returns the file name to store a download under. The name is
+// kept so that archive detection keeps working on cached artifacts.
+func artifactName(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		name = "download"
+	}
+	return name, nil
+}
+
+// readFileOrURL reads a small file such as a checksums or signature file
+// from a local path or an HTTP(S) URL.
+func readFileOrURL(src string) ([]byte, error) {
+	if !isURL(src) {
+		return os.ReadFile(src)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, stop, err := httpGet(req)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", src, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func readCacheEntry(path string) (cacheEntry, error) {
+	var entry cacheEntry
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal(data, &entry)
+	return entry, err
+}
+
+func writeCacheEntry(path string, entry cacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
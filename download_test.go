@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestArtifactServer(t *testing.T, content []byte) (*httptest.Server, *int32, *string) {
+	t.Helper()
+	var requests int32
+	var lastRange string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		lastRange = r.Header.Get("Range")
+		w.Header().Set("ETag", testETag(content))
+		http.ServeContent(w, r, "artifact", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests, &lastRange
+}
+
+func testETag(content []byte) string {
+	return `"` + sha256Hex(content)[:16] + `"`
+}
+
+func TestDownloadArtifact_UsesCache(t *testing.T) {
+	baseDir := t.TempDir()
+	content := []byte("binary contents")
+	srv, requests, _ := newTestArtifactServer(t, content)
+	url := srv.URL + "/dl/tool"
+
+	path, err := downloadArtifact(baseDir, url, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !bytes.Equal(data, content) {
+		t.Errorf("unexpected content: %q", data)
+	}
+
+	// Stored by content hash and keeps the URL's file name
+	want := cachedArtifactPath(baseDir, sha256Hex(content), "tool")
+	if path != want {
+		t.Errorf("expected %s, got %s", want, path)
+	}
+
+	// Second download is served from the cache
+	again, err := downloadArtifact(baseDir, url, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again != path {
+		t.Errorf("expected cached path %s, got %s", path, again)
+	}
+	if *requests != 1 {
+		t.Errorf("expected 1 request, got %d", *requests)
+	}
+}
+
+func TestDownloadArtifact_Resume(t *testing.T) {
+	baseDir := t.TempDir()
+	content := []byte(strings.Repeat("0123456789", 100))
+	srv, requests, lastRange := newTestArtifactServer(t, content)
+	url := srv.URL + "/tool.tar.gz"
+
+	// Simulate an interrupted download
+	partialDir := filepath.Join(cacheDir(baseDir), "partial")
+	os.MkdirAll(partialDir, 0755)
+	partial := filepath.Join(partialDir, sha256Hex([]byte(url)))
+	os.WriteFile(partial, content[:400], 0644)
+	os.WriteFile(validatorPath(partial), []byte(testETag(content)), 0644)
+
+	path, err := downloadArtifact(baseDir, url, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if *requests != 1 {
+		t.Errorf("expected 1 request, got %d", *requests)
+	}
+	if *lastRange != "bytes=400-" {
+		t.Errorf("expected range request from byte 400, got %q", *lastRange)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !bytes.Equal(data, content) {
+		t.Error("resumed download does not match the original content")
+	}
+}
+
+func TestDownloadArtifact_ResumeChanged(t *testing.T) {
+	old := []byte(strings.Repeat("old-", 250))
+	content := []byte(strings.Repeat("new+", 300))
+
+	tests := map[string]string{
+		"changed upstream": testETag(old), // If-Range no longer matches
+		"no validator":     "",            // the partial cannot be checked
+	}
+	for name, validator := range tests {
+		baseDir := t.TempDir()
+		srv, _, lastRange := newTestArtifactServer(t, content)
+		url := srv.URL + "/tool.tar.gz"
+
+		partial := filepath.Join(cacheDir(baseDir), "partial", sha256Hex([]byte(url)))
+		os.MkdirAll(filepath.Dir(partial), 0755)
+		os.WriteFile(partial, old[:400], 0644)
+		if validator != "" {
+			os.WriteFile(validatorPath(partial), []byte(validator), 0644)
+		}
+
+		path, err := downloadArtifact(baseDir, url, "")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if data, _ := os.ReadFile(path); !bytes.Equal(data, content) {
+			t.Errorf("%s: expected the new file, got a spliced one", name)
+		}
+		if validator == "" && *lastRange != "" {
+			t.Errorf("%s: expected no range request, got %q", name, *lastRange)
+		}
+		if _, err := os.Stat(validatorPath(partial)); !os.IsNotExist(err) {
+			t.Errorf("%s: expected the validator to be removed", name)
+		}
+	}
+}
+
+func TestDownloadArtifact_WrongContentRange(t *testing.T) {
+	baseDir := t.TempDir()
+	content := []byte(strings.Repeat("0123456789", 100))
+	var requests int32
+	// The server answers every range request with the whole file
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("ETag", testETag(content))
+		if r.Header.Get("Range") != "" {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		w.Write(content)
+	}))
+	defer srv.Close()
+	url := srv.URL + "/tool.tar.gz"
+
+	partial := filepath.Join(cacheDir(baseDir), "partial", sha256Hex([]byte(url)))
+	os.MkdirAll(filepath.Dir(partial), 0755)
+	os.WriteFile(partial, content[:400], 0644)
+	os.WriteFile(validatorPath(partial), []byte(testETag(content)), 0644)
+
+	path, err := downloadArtifact(baseDir, url, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, content) {
+		t.Error("expected the download to start over")
+	}
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestDownloadArtifact_Concurrent(t *testing.T) {
+	baseDir := t.TempDir()
+	content := []byte(strings.Repeat("0123456789", 1000))
+	var requests int32
+	// Send the file in slow chunks so that the downloads overlap
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("ETag", testETag(content))
+		for chunk := range slices.Chunk(content, 1000) {
+			w.Write(chunk)
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	url := srv.URL + "/tool.tar.gz"
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path, err := downloadArtifact(baseDir, url, "")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if data, _ := os.ReadFile(path); !bytes.Equal(data, content) {
+				t.Error("concurrent downloads corrupted the artifact")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The downloads that waited used the artifact the first one stored
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := map[string][3]int64{
+		"bytes 400-999/1000": {400, 1000, 1},
+		"bytes 0-99/*":       {0, -1, 1},
+		"bytes */1000":       {-1, 1000, 1},
+		"bytes 400/1000":     {0, 0, 0},
+		"items 0-1/2":        {0, 0, 0},
+		"":                   {0, 0, 0},
+	}
+	for value, want := range tests {
+		start, size, ok := parseContentRange(value)
+		if start != want[0] || size != want[1] || ok != (want[2] == 1) {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", value, start, size, ok)
+		}
+	}
+}
+
+func TestDownloadArtifact_HTTPError(t *testing.T) {
+	baseDir := t.TempDir()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	if _, err := downloadArtifact(baseDir, srv.URL+"/missing.tar.gz", ""); err == nil {
+		t.Error("expected error for 404 response")
+	}
+}
+
+func TestDownloadArtifact_Timeouts(t *testing.T) {
+	transport := httpClient.Transport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 100 * time.Millisecond
+	defer func(client *http.Client, stall time.Duration) {
+		httpClient, downloadStallTimeout = client, stall
+	}(httpClient, downloadStallTimeout)
+	httpClient, downloadStallTimeout = &http.Client{Transport: transport}, 100*time.Millisecond
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/body.tar.gz" {
+			w.Header().Set("Content-Length", "1000")
+			w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	for _, name := range []string{"headers.tar.gz", "body.tar.gz"} {
+		done := make(chan error, 1)
+		go func() {
+			_, err := downloadArtifact(t.TempDir(), srv.URL+"/"+name, "")
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Errorf("%s: expected an error from a stalled server", name)
+			} else if name == "body.tar.gz" && !strings.Contains(err.Error(), "no data received") {
+				t.Errorf("%s: unexpected error: %v", name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: download did not time out", name)
+		}
+	}
+}
+
+func TestInstallPath_URL(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+
+	archive := filepath.Join(t.TempDir(), "app.tar.gz")
+	writeTestTarGz(t, archive, []testArchiveEntry{
+		{name: "app/bin/app", body: "#!/bin/sh\n", mode: 0755},
+	})
+	content, _ := os.ReadFile(archive)
+	srv, requests, _ := newTestArtifactServer(t, content)
+
+	url := srv.URL + "/app.tar.gz"
+	if err := installPath(baseDir, url, "app", "1.0.0", installOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "app", "1.0.0", "bin", "app")); err != nil {
+		t.Errorf("expected installed binary: %v", err)
+	}
+
+	// Reinstalling reuses the cached artifact
+	if err := installPath(baseDir, url, "app", "1.0.0", installOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *requests != 1 {
+		t.Errorf("expected 1 request, got %d", *requests)
+	}
+
+	// The cache is not listed as an app
+	apps, _ := listApps(baseDir)
+	if len(apps) != 1 || apps[0] != "app" {
+		t.Errorf("expected only app, got %v", apps)
+	}
+}
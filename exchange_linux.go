@@ -0,0 +1,18 @@
+//go:build linux
+
+package main
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// exchangePaths atomically swaps the directory entries a and b using
+// renameat2(RENAME_EXCHANGE), falling back to two renames on filesystems
+// that do not support it.
+func exchangePaths(a, b string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+	if err == unix.ENOSYS || err == unix.EINVAL {
+		return exchangeByRename(a, b)
+	}
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// exchangePaths swaps the directory entries a and b. Without an atomic
+// exchange primitive this falls back to two renames.
+func exchangePaths(a, b string) error {
+	return exchangeByRename(a, b)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// execSelection is an app version selected for lav exec.
+type execSelection struct {
+	app     string
+	version string
+}
+
+// parseExecSpec splits an "app@version" spec. Without "@version" the
+// version in effect in the working directory is used, as for shims.
+func parseExecSpec(spec string) (string, string, error) {
+	app, version, _ := strings.Cut(spec, "@")
+	if err := validateName("app", app); err != nil {
+		return "", "", err
+	}
+	if strings.Contains(spec, "@") && version == "" {
+		return "", "", fmt.Errorf("missing version in %q", spec)
+	}
+	return app, version, nil
+}
+
+// resolveExecSpecs resolves each "app@version" spec, which may use "latest"
+// or a constraint, to an installed version.
+func resolveExecSpecs(baseDir string, specs []string) ([]execSelection, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var selections []execSelection
+	for _, spec := range specs {
+		app, versionSpec, err := parseExecSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		var version string
+		if versionSpec == "" {
+			version, _, err = resolveActiveVersion(baseDir, app, cwd)
+		} else {
+			versions, listErr := listVersions(baseDir, app)
+			if listErr != nil && !os.IsNotExist(listErr) {
+				return nil, listErr
+			}
+			version, err = resolveVersion(app, versions, versionSpec, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, execSelection{app: app, version: version})
+	}
+	return selections, nil
+}
+
+// execEnv returns environ with the bin directories of selections prepended
+// to PATH, first selection first, and LAV_<APP>_VERSION set for each so that
+// shims run the same versions. Variables from the app's [apps.<name>.env]
+// config table are set too.
+func execEnv(baseDir string, selections []execSelection, environ []string, cfg config) []string {
+	env := make(map[string]string)
+	var order []string
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		if _, ok := env[key]; !ok {
+			order = append(order, key)
+		}
+		env[key] = value
+	}
+	set := func(key, value string) {
+		if _, ok := env[key]; !ok {
+			order = append(order, key)
+		}
+		env[key] = value
+	}
+
+	if abs, err := filepath.Abs(baseDir); err == nil {
+		baseDir = abs
+	}
+
+	var binDirs []string
+	for _, sel := range selections {
+		prefix := filepath.Join(baseDir, sel.app, sel.version)
+		binDirs = append(binDirs, filepath.Join(prefix, "bin"))
+		set(versionEnvVar(sel.app), sel.version)
+
+		replacer := strings.NewReplacer("{version}", sel.version, "{prefix}", prefix)
+		for _, key := range slices.Sorted(maps.Keys(cfg.Apps[sel.app].Env)) {
+			set(key, replacer.Replace(cfg.Apps[sel.app].Env[key]))
+		}
+	}
+
+	if env["PATH"] != "" {
+		binDirs = append(binDirs, env["PATH"])
+	}
+	set("PATH", strings.Join(binDirs, string(os.PathListSeparator)))
+
+	result := make([]string, 0, len(order))
+	for _, key := range order {
+		result = append(result, key+"="+env[key])
+	}
+	return result
+}
+
+// lookPathIn finds the executable name in pathEnv, a PATH-style list.
+// Names containing a slash are returned as they are.
+func lookPathIn(name, pathEnv string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			dir = "."
+		}
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return path, nil
+		}
+	}
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+// runExec replaces the lav process with command, run with the versions
+// selected by specs on PATH. The global current versions are not touched.
+func runExec(baseDir string, specs, command []string) error {
+	selections, err := resolveExecSpecs(baseDir, specs)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	env := execEnv(baseDir, selections, os.Environ(), cfg)
+	path, err := lookPathIn(command[0], lookupEnv(env, "PATH"))
+	if err != nil {
+		return err
+	}
+	return execProcess(path, command, env)
+}
@@ -0,0 +1,34 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// execProcess runs path with argv and env in place of lav. Without exec(2)
+// the command runs as a child on lav's standard streams, and lav exits with
+// its exit status once it is done.
+func execProcess(path string, argv, env []string) error {
+	cmd := exec.Command(path)
+	cmd.Args = argv
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	// Ctrl+C reaches the child through the console; lav keeps waiting for it
+	signal.Ignore(os.Interrupt)
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
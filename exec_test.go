@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseExecSpec(t *testing.T) {
+	app, version, err := parseExecSpec("go@1.22.0")
+	if err != nil || app != "go" || version != "1.22.0" {
+		t.Errorf("unexpected result: %s %s %v", app, version, err)
+	}
+	app, version, err = parseExecSpec("node")
+	if err != nil || app != "node" || version != "" {
+		t.Errorf("unexpected result: %s %s %v", app, version, err)
+	}
+	for _, spec := range []string{"go@", "@1.22.0", "../go@1"} {
+		if _, _, err := parseExecSpec(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestResolveExecSpecs(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("LAV_NODE_VERSION", "")
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.4", "go")
+	installTestVersion(t, baseDir, "node", "22.1.0", "node")
+
+	selections, err := resolveExecSpecs(baseDir, []string{"go@1.22", "node"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(selections) != 2 || selections[0].version != "1.22.0" || selections[1].version != "22.1.0" {
+		t.Errorf("unexpected selections: %+v", selections)
+	}
+	if current, _ := getCurrentVersion(baseDir, "go"); current != "1.23.4" {
+		t.Errorf("current version should not change, got %s", current)
+	}
+
+	if _, err := resolveExecSpecs(baseDir, []string{"go@1.21"}); err == nil {
+		t.Error("expected error for a version that is not installed")
+	}
+}
+
+func TestExecEnv(t *testing.T) {
+	baseDir := t.TempDir()
+	cfg := config{Apps: map[string]appConfig{
+		"go": {Env: map[string]string{"GOROOT": "{prefix}", "GOTOOLCHAIN": "go{version}"}},
+	}}
+	selections := []execSelection{{app: "go", version: "1.22.0"}, {app: "node", version: "22.1.0"}}
+
+	env := execEnv(baseDir, selections, []string{"HOME=/home/me", "PATH=/usr/bin"}, cfg)
+	vars := make(map[string]string)
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		vars[key] = value
+	}
+
+	goBin := filepath.Join(baseDir, "go", "1.22.0", "bin")
+	nodeBin := filepath.Join(baseDir, "node", "22.1.0", "bin")
+	if want := strings.Join([]string{goBin, nodeBin, "/usr/bin"}, string(os.PathListSeparator)); vars["PATH"] != want {
+		t.Errorf("expected PATH %s, got %s", want, vars["PATH"])
+	}
+	if vars["LAV_GO_VERSION"] != "1.22.0" || vars["LAV_NODE_VERSION"] != "22.1.0" {
+		t.Errorf("version variables not set: %v", vars)
+	}
+	if vars["GOROOT"] != filepath.Join(baseDir, "go", "1.22.0") || vars["GOTOOLCHAIN"] != "go1.22.0" {
+		t.Errorf("app env not expanded: %v", vars)
+	}
+	if vars["HOME"] != "/home/me" {
+		t.Errorf("existing variables should be kept: %v", vars)
+	}
+}
+
+func TestLookPathIn(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "tool"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "data"), []byte("x"), 0644)
+
+	if path, err := lookPathIn("tool", "/nonexistent"+string(os.PathListSeparator)+dir); err != nil || path != filepath.Join(dir, "tool") {
+		t.Errorf("unexpected result: %s %v", path, err)
+	}
+	if _, err := lookPathIn("data", dir); err == nil {
+		t.Error("non-executable files should not be found")
+	}
+}
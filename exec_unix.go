@@ -0,0 +1,10 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// execProcess replaces the lav process with path, run with argv and env.
+func execProcess(path string, argv, env []string) error {
+	return syscall.Exec(path, argv, env)
+}
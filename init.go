@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// initShells lists the shells lav init supports.
+var initShells = []string{"bash", "zsh", "fish"}
+
+// Completion functions. They pass the words before the cursor to the hidden
+// __complete command and offer its output.
+const (
+	bashCompletion = `_lav_complete() {
+    local IFS=$'\n'
+    COMPREPLY=($(compgen -W "$(command lav __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" 2>/dev/null)" -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -o default -F _lav_complete lav
+`
+	zshCompletion = `_lav() {
+    local -a candidates
+    candidates=("${(@f)$(command lav __complete "${(@)words[2,CURRENT-1]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    if (( ${#candidates} )); then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+if (( $+functions[compdef] )); then
+    compdef _lav lav
+fi
+`
+	fishCompletion = `complete -c lav -a '(command lav __complete (commandline -opc)[2..-1] 2>/dev/null)'
+`
+)
+
+// Hooks that apply project version files whenever the working directory
+// changes, and a lav function that makes lav shell change the current shell.
+const (
+	bashHook = `_lav_hook() {
+    [ "$PWD" = "${_LAV_HOOK_PWD-}" ] && return
+    _LAV_HOOK_PWD=$PWD
+    eval "$(command lav __hook bash)"
+}
+case ";${PROMPT_COMMAND-};" in
+    *";_lav_hook;"*) ;;
+    *) PROMPT_COMMAND="_lav_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`
+	zshHook = `_lav_hook() {
+    eval "$(command lav __hook zsh)"
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _lav_hook
+_lav_hook
+`
+	posixShellFunction = `lav() {
+    case "${1-} ${2-}" in
+        "shell --unset"*) eval "$(command lav "$@")" ;;
+        "shell -"* | "shell ") command lav "$@" ;;
+        "shell "*) shift; eval "$(command lav shell --export "$@")" ;;
+        *) command lav "$@" ;;
+    esac
+}
+`
+	fishHook = `function _lav_hook --on-variable PWD
+    command lav __hook fish | source
+end
+_lav_hook
+function lav
+    if test "$argv[1]" = shell; and set -q argv[2]
+        switch $argv[2]
+            case --unset
+                command lav $argv | source
+            case '-*'
+                command lav $argv
+            case '*'
+                command lav shell --export $argv[2..-1] | source
+        end
+    else
+        command lav $argv
+    end
+end
+`
+)
+
+// initScript returns the code lav init prints for shell: PATH entries for
+// the bin directories missing from pathEnv, completions and, with hook, the
+// project version hook.
+func initScript(shell string, binDirs []string, pathEnv string, hook bool) (string, error) {
+	if !slices.Contains(initShells, shell) {
+		return "", fmt.Errorf("unsupported shell %q (supported: %s)", shell, strings.Join(initShells, ", "))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# lav shell integration for %s\n", shell)
+
+	missing := binDirsNotOnPath(binDirs, pathEnv)
+	for i := len(missing) - 1; i >= 0; i-- {
+		if shell == "fish" {
+			fmt.Fprintf(&b, "set -gx PATH %s $PATH\n", shellQuote(missing[i]))
+		} else {
+			fmt.Fprintf(&b, "export PATH=%s:\"$PATH\"\n", shellQuote(missing[i]))
+		}
+	}
+
+	switch shell {
+	case "bash":
+		b.WriteString(bashCompletion)
+	case "zsh":
+		b.WriteString(zshCompletion)
+	case "fish":
+		b.WriteString(fishCompletion)
+	}
+
+	if hook {
+		switch shell {
+		case "bash":
+			b.WriteString(bashHook + posixShellFunction)
+		case "zsh":
+			b.WriteString(zshHook + posixShellFunction)
+		case "fish":
+			b.WriteString(fishHook)
+		}
+	}
+	return b.String(), nil
+}
+
+// pathHint returns a note telling the user how to add the bin directories
+// missing from pathEnv to PATH, or "" if none are missing.
+func pathHint(binDirs []string, pathEnv string) string {
+	missing := binDirsNotOnPath(binDirs, pathEnv)
+	if len(missing) == 0 {
+		return ""
+	}
+	shell := detectShell()
+	if !slices.Contains(initShells, shell) {
+		shell = "bash"
+	}
+	startup := "~/." + shell + "rc"
+	command := fmt.Sprintf(`eval "$(lav init %s)"`, shell)
+	if shell == "fish" {
+		startup = "~/.config/fish/config.fish"
+		command = "lav init fish | source"
+	}
+	return fmt.Sprintf("Note: %s is not on PATH; add '%s' to %s", strings.Join(missing, ", "), command, startup)
+}
+
+// hookChanges returns the commands the lav init hook runs in dir: the
+// versions set by project version files are applied as lav shell --export
+// would, and those applied for the previous directory are undone. LAV_HOOK
+// lists the applied versions; apps in a lav shell session are left alone.
+// Project versions that are not installed are returned as warnings.
+func hookChanges(baseDir, dir string, environ []string, cfg config, shell string) ([]string, []string, error) {
+	versions, err := projectVersions(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session := make(map[string]bool)
+	for _, entry := range strings.Fields(lookupEnv(environ, "LAV_SHELL")) {
+		app, _, _ := strings.Cut(entry, "@")
+		session[app] = true
+	}
+
+	var selections []execSelection
+	var applied, warnings []string
+	for _, app := range slices.Sorted(maps.Keys(versions)) {
+		if session[app] {
+			continue
+		}
+		installed, err := listVersions(baseDir, app)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, nil, err
+		}
+		version, err := resolveVersion(app, installed, versions[app].version, false)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%v (from %s)", err, versions[app].file))
+			continue
+		}
+		selections = append(selections, execSelection{app: app, version: version})
+		applied = append(applied, app+"@"+version)
+	}
+
+	previous := strings.Fields(lookupEnv(environ, "LAV_HOOK"))
+	if slices.Equal(previous, applied) {
+		return nil, warnings, nil
+	}
+
+	var undo []string
+	for _, entry := range previous {
+		if app, _, _ := strings.Cut(entry, "@"); !session[app] {
+			undo = append(undo, app)
+		}
+	}
+	env := withoutApps(baseDir, undo, environ, cfg)
+	env = slices.DeleteFunc(env, func(kv string) bool {
+		return strings.HasPrefix(kv, "LAV_HOOK=")
+	})
+	if len(applied) > 0 {
+		env = append(env, "LAV_HOOK="+strings.Join(applied, " "))
+	}
+	return envChanges(environ, execEnv(baseDir, selections, env, cfg), shell), warnings, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestInitScript(t *testing.T) {
+	script, err := initScript("bash", []string{"/opt/lav/bin", "/usr/bin"}, "/usr/bin:/bin", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(script, `export PATH='/opt/lav/bin':"$PATH"`) {
+		t.Errorf("missing bin directory should be added to PATH:\n%s", script)
+	}
+	if strings.Contains(script, "'/usr/bin'") {
+		t.Errorf("bin directories on PATH should not be added again:\n%s", script)
+	}
+	if !strings.Contains(script, "complete -o default -F _lav_complete lav") {
+		t.Errorf("expected completion setup:\n%s", script)
+	}
+	if strings.Contains(script, "_lav_hook") {
+		t.Errorf("hook should only be set up with --hook:\n%s", script)
+	}
+
+	script, err = initScript("fish", []string{"/opt/lav/bin"}, "/usr/bin", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(script, "set -gx PATH '/opt/lav/bin' $PATH") || !strings.Contains(script, "--on-variable PWD") {
+		t.Errorf("unexpected fish script:\n%s", script)
+	}
+
+	if _, err := initScript("tcsh", nil, "", false); err == nil {
+		t.Error("expected error for an unsupported shell")
+	}
+}
+
+func TestPathHint(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/zsh")
+	if hint := pathHint([]string{"/usr/bin"}, "/usr/bin"); hint != "" {
+		t.Errorf("expected no hint, got %q", hint)
+	}
+	hint := pathHint([]string{"/opt/lav/bin"}, "/usr/bin")
+	if !strings.Contains(hint, "/opt/lav/bin") || !strings.Contains(hint, `eval "$(lav init zsh)"`) {
+		t.Errorf("unexpected hint: %q", hint)
+	}
+}
+
+func TestHookChanges(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.4", "go")
+
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, ".lav.toml"), []byte("go = \"1.22\"\nnode = \"22\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goBin := filepath.Join(baseDir, "go", "1.22.0", "bin")
+
+	// Entering the project applies its versions
+	lines, warnings, err := hookChanges(baseDir, project, []string{"PATH=/usr/bin"}, config{}, "bash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"export PATH='" + goBin + ":/usr/bin'",
+		"export LAV_HOOK='go@1.22.0'",
+		"export LAV_GO_VERSION='1.22.0'",
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("expected %v, got %v", want, lines)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "node") {
+		t.Errorf("expected a warning for node, got %v", warnings)
+	}
+
+	// Nothing changes while the versions stay the same
+	inProject := []string{"PATH=" + goBin + ":/usr/bin", "LAV_HOOK=go@1.22.0", "LAV_GO_VERSION=1.22.0"}
+	if lines, _, _ := hookChanges(baseDir, project, inProject, config{}, "bash"); len(lines) != 0 {
+		t.Errorf("expected no changes, got %v", lines)
+	}
+
+	// Leaving the project undoes them
+	lines, _, err = hookChanges(baseDir, t.TempDir(), inProject, config{}, "bash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = []string{"export PATH='/usr/bin'", "unset LAV_HOOK", "unset LAV_GO_VERSION"}
+	if !slices.Equal(lines, want) {
+		t.Errorf("expected %v, got %v", want, lines)
+	}
+
+	// Apps in a lav shell session are left alone
+	lines, _, _ = hookChanges(baseDir, project, []string{"PATH=/usr/bin", "LAV_SHELL=go@1.23.4"}, config{}, "bash")
+	if len(lines) != 0 {
+		t.Errorf("expected no changes in a lav shell session, got %v", lines)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import "os"
+
+// lockPath takes an exclusive lock on the file at path, creating it if
+// needed and waiting while another process holds the lock. The file is
+// left in place so that every process locks the same one. The returned
+// function releases the lock.
+func lockPath(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"crypto/rand"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 // version is set by ldflags during build
@@ -31,6 +36,71 @@ func getBaseDir() (string, error) {
 	return filepath.Join(home, ".local", "share", "lav"), nil
 }
 
+// binDirFlag holds the directories given with the global --bin-dir flag.
+var binDirFlag []string
+
+// getBinDirs returns the directories where executables are linked.
+func getBinDirs() ([]string, error) {
+	// 1. --bin-dir flag (highest priority)
+	if len(binDirFlag) > 0 {
+		return binDirFlag, nil
+	}
+
+	// 2. LAV_BIN_DIR environment variable, a list like PATH
+	if lavBinDir := os.Getenv("LAV_BIN_DIR"); lavBinDir != "" {
+		return filepath.SplitList(lavBinDir), nil
+	}
+
+	// 3. bin_dir in the config file
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.BinDirs) > 0 {
+		return cfg.BinDirs, nil
+	}
+
+	// 4. Fallback to ~/.local/bin
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{filepath.Join(home, ".local", "bin")}, nil
+}
+
+// extractBinDirFlag removes every --bin-dir flag from args, which may
+// appear anywhere before "--", and records its values in binDirFlag.
+func extractBinDirFlag(args []string) ([]string, error) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		value, ok := strings.CutPrefix(arg, "--bin-dir=")
+		if !ok && arg != "--bin-dir" {
+			rest = append(rest, arg)
+			continue
+		}
+		if !ok {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag --bin-dir requires a value")
+			}
+			i++
+			value = args[i]
+		}
+		if value == "" {
+			return nil, fmt.Errorf("flag --bin-dir requires a value")
+		}
+		binDirFlag = append(binDirFlag, filepath.SplitList(value)...)
+	}
+
+	return rest, nil
+}
+
 func listApps(baseDir string) ([]string, error) {
 	entries, err := os.ReadDir(baseDir)
 	if err != nil {
@@ -39,7 +109,8 @@ func listApps(baseDir string) ([]string, error) {
 
 	var apps []string
 	for _, entry := range entries {
-		if entry.IsDir() {
+		// Skip hidden directories such as the download cache
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
 			apps = append(apps, entry.Name())
 		}
 	}
@@ -57,12 +128,13 @@ func listVersions(baseDir, app string) ([]string, error) {
 
 	var versions []string
 	for _, entry := range entries {
-		if entry.IsDir() && entry.Name() != "current" {
+		// Skip the current symlink and hidden work directories
+		if entry.IsDir() && entry.Name() != "current" && !strings.HasPrefix(entry.Name(), ".") {
 			versions = append(versions, entry.Name())
 		}
 	}
 
-	sort.Strings(versions)
+	sortVersions(versions)
 	return versions, nil
 }
 
@@ -79,7 +151,7 @@ func getCurrentVersion(baseDir, app string) (string, error) {
 	return filepath.Base(target), nil
 }
 
-func switchVersion(baseDir, app, version string) error {
+func switchVersion(baseDir, app, version string, force bool) error {
 	appDir := filepath.Join(baseDir, app)
 	versionDir := filepath.Join(appDir, version)
 
@@ -87,24 +159,42 @@ func switchVersion(baseDir, app, version string) error {
 		return fmt.Errorf("version %s does not exist for %s", version, app)
 	}
 
-	currentLink := filepath.Join(appDir, "current")
+	return activateVersion(baseDir, app, version, force)
+}
 
-	if info, err := os.Lstat(currentLink); err == nil {
-		if info.Mode()&os.ModeSymlink != 0 {
-			if err := os.Remove(currentLink); err != nil {
-				return err
-			}
-		}
+// replaceSymlink atomically points link at target. The new symlink is
+// created under a temporary name and renamed over link, so there is no
+// moment at which link is missing or dangling. A link path occupied by
+// anything other than a symlink is left alone and reported as an error.
+func replaceSymlink(target, link string) error {
+	if info, err := os.Lstat(link); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("%s exists and is not a symlink", link)
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
 	}
+	tmpLink := filepath.Join(filepath.Dir(link), fmt.Sprintf(".%s.tmp-%x", filepath.Base(link), suffix))
 
-	if err := os.Symlink(version, currentLink); err != nil {
+	if err := os.Symlink(target, tmpLink); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpLink, link); err != nil {
+		os.Remove(tmpLink)
 		return err
 	}
 
 	return nil
 }
 
-func installBinary(baseDir, binaryPath, appName, version string) error {
+func installBinary(baseDir, binaryPath, appName, version string, force bool) error {
+	if err := validateName("app", appName); err != nil {
+		return err
+	}
+	if err := validateVersionName(version); err != nil {
+		return err
+	}
 	// Get absolute path of the binary
 	absPath, err := filepath.Abs(binaryPath)
 	if err != nil {
@@ -116,74 +206,42 @@ func installBinary(baseDir, binaryPath, appName, version string) error {
 		return fmt.Errorf("binary does not exist: %s", absPath)
 	}
 
-	// Get binary name (without path)
-	binaryName := filepath.Base(absPath)
-
-	// Create version directory: ~/.local/share/apps/<app>/<version>/bin/
-	versionBinDir := filepath.Join(baseDir, appName, version, "bin")
-	if err := os.MkdirAll(versionBinDir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	// Copy binary to version directory
-	destPath := filepath.Join(versionBinDir, binaryName)
-	if err := copyFile(absPath, destPath); err != nil {
-		return fmt.Errorf("failed to copy binary: %w", err)
-	}
-
-	// Make binary executable
-	if err := os.Chmod(destPath, 0755); err != nil {
-		return fmt.Errorf("failed to make binary executable: %w", err)
+	staged, err := newStagingDir(baseDir, appName, version)
+	if err != nil {
+		return err
 	}
+	defer os.RemoveAll(staged)
 
-	// Create/update current symlink
-	appDir := filepath.Join(baseDir, appName)
-	currentLink := filepath.Join(appDir, "current")
-
-	// Remove existing symlink if it exists
-	if info, err := os.Lstat(currentLink); err == nil {
-		if info.Mode()&os.ModeSymlink != 0 {
-			if err := os.Remove(currentLink); err != nil {
-				return fmt.Errorf("failed to remove existing symlink: %w", err)
-			}
-		}
+	if err := stageBinary(absPath, staged); err != nil {
+		return err
 	}
 
-	// Create current symlink
-	if err := os.Symlink(version, currentLink); err != nil {
-		return fmt.Errorf("failed to create current symlink: %w", err)
+	// Move into place: ~/.local/share/apps/<app>/<version>/
+	if err := commitVersion(baseDir, staged, appName, version); err != nil {
+		return err
 	}
 
-	// Get user's home directory for ~/.local/bin
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
+	return activateVersion(baseDir, appName, version, force)
+}
 
-	// Create ~/.local/bin if it doesn't exist
-	localBinDir := filepath.Join(home, ".local", "bin")
-	if err := os.MkdirAll(localBinDir, 0755); err != nil {
-		return fmt.Errorf("failed to create ~/.local/bin: %w", err)
+// stageBinary lays out the binary at src in the staging directory staged
+// as bin/<binary>.
+func stageBinary(src, staged string) error {
+	stagedBinDir := filepath.Join(staged, "bin")
+	if err := os.MkdirAll(stagedBinDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Create/update symlink in ~/.local/bin
-	binLink := filepath.Join(localBinDir, binaryName)
-	relTarget := filepath.Join("..", "share", "lav", appName, "current", "bin", binaryName)
-
-	// Remove existing symlink if it exists
-	if info, err := os.Lstat(binLink); err == nil {
-		if info.Mode()&os.ModeSymlink != 0 {
-			if err := os.Remove(binLink); err != nil {
-				return fmt.Errorf("failed to remove existing bin symlink: %w", err)
-			}
-		}
+	// Copy binary to staging directory
+	destPath := filepath.Join(stagedBinDir, filepath.Base(src))
+	if err := copyFile(src, destPath); err != nil {
+		return fmt.Errorf("failed to copy binary: %w", err)
 	}
 
-	// Create bin symlink
-	if err := os.Symlink(relTarget, binLink); err != nil {
-		return fmt.Errorf("failed to create bin symlink: %w", err)
+	// Make binary executable
+	if err := os.Chmod(destPath, 0755); err != nil {
+		return fmt.Errorf("failed to make binary executable: %w", err)
 	}
-
 	return nil
 }
 
@@ -254,56 +312,13 @@ func copyDir(src, dst string) error {
 	return nil
 }
 
-func createBinSymlinks(baseDir, appName string) error {
-	// Get user's home directory for ~/.local/bin
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	// Create ~/.local/bin if it doesn't exist
-	localBinDir := filepath.Join(home, ".local", "bin")
-	if err := os.MkdirAll(localBinDir, 0755); err != nil {
-		return fmt.Errorf("failed to create ~/.local/bin: %w", err)
-	}
-
-	// Path to the current/bin directory
-	currentBinDir := filepath.Join(baseDir, appName, "current", "bin")
-
-	// Read all executables in current/bin
-	entries, err := os.ReadDir(currentBinDir)
-	if err != nil {
-		return fmt.Errorf("failed to read bin directory: %w", err)
+func installDirectory(baseDir, srcDir, appName, version string, force bool) error {
+	if err := validateName("app", appName); err != nil {
+		return err
 	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		binName := entry.Name()
-		binLink := filepath.Join(localBinDir, binName)
-		relTarget := filepath.Join("..", "share", "lav", appName, "current", "bin", binName)
-
-		// Remove existing symlink if it exists
-		if info, err := os.Lstat(binLink); err == nil {
-			if info.Mode()&os.ModeSymlink != 0 {
-				if err := os.Remove(binLink); err != nil {
-					return fmt.Errorf("failed to remove existing bin symlink: %w", err)
-				}
-			}
-		}
-
-		// Create bin symlink
-		if err := os.Symlink(relTarget, binLink); err != nil {
-			return fmt.Errorf("failed to create bin symlink for %s: %w", binName, err)
-		}
+	if err := validateVersionName(version); err != nil {
+		return err
 	}
-
-	return nil
-}
-
-func installDirectory(baseDir, srcDir, appName, version string) error {
 	// Get absolute path of the source directory
 	absPath, err := filepath.Abs(srcDir)
 	if err != nil {
@@ -326,117 +341,829 @@ func installDirectory(baseDir, srcDir, appName, version string) error {
 		return fmt.Errorf("bin/ directory does not exist in source directory")
 	}
 
-	// Create version directory: ~/.local/share/apps/<app>/<version>/
-	versionDir := filepath.Join(baseDir, appName, version)
-	if err := os.MkdirAll(versionDir, 0755); err != nil {
-		return fmt.Errorf("failed to create version directory: %w", err)
+	staged, err := newStagingDir(baseDir, appName, version)
+	if err != nil {
+		return err
 	}
+	defer os.RemoveAll(staged)
 
 	// Copy entire directory structure
-	if err := copyDir(absPath, versionDir); err != nil {
+	if err := copyDir(absPath, staged); err != nil {
 		return fmt.Errorf("failed to copy directory: %w", err)
 	}
 
+	// Move into place: ~/.local/share/apps/<app>/<version>/
+	if err := commitVersion(baseDir, staged, appName, version); err != nil {
+		return err
+	}
+
+	return activateVersion(baseDir, appName, version, force)
+}
+
+// activateVersion points the app's current symlink at version and syncs
+// the bin directories with the executables in its bin/ directory. Nothing
+// changes if that would replace a link appName does not own, unless force
+// is set. If the links cannot be synced, current goes back to the version
+// it pointed at before.
+func activateVersion(baseDir, appName, version string, force bool) error {
+	binDirs, err := absBinDirs()
+	if err != nil {
+		return err
+	}
+	// Hold the registry lock from the conflict check until the links are
+	// synced, so that no other lav claims a link in between
+	unlock, err := lockLinkRegistry(baseDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		return err
+	}
+	if err := checkBinLinks(baseDir, reg, binDirs, appName, version, force); err != nil {
+		return err
+	}
+
 	// Create/update current symlink
 	appDir := filepath.Join(baseDir, appName)
 	currentLink := filepath.Join(appDir, "current")
+	previous, _ := os.Readlink(currentLink)
 
-	// Remove existing symlink if it exists
-	if info, err := os.Lstat(currentLink); err == nil {
-		if info.Mode()&os.ModeSymlink != 0 {
-			if err := os.Remove(currentLink); err != nil {
-				return fmt.Errorf("failed to remove existing symlink: %w", err)
-			}
+	if err := replaceSymlink(version, currentLink); err != nil {
+		return fmt.Errorf("failed to update current symlink: %w", err)
+	}
+
+	// Link the executables in bin/ and drop links to ones it no longer has
+	executables, err := appExecutables(baseDir, appName, "current")
+	if err == nil {
+		_, err = reconcileLinks(baseDir, reg, binDirs, appName, executables, force)
+	}
+	if err != nil {
+		restoreCurrent(baseDir, reg, binDirs, appName, previous, force)
+		reg.save(baseDir)
+		return fmt.Errorf("failed to update bin symlinks: %w", err)
+	}
+
+	if err := reg.save(baseDir); err != nil {
+		return fmt.Errorf("failed to save link registry: %w", err)
+	}
+	return nil
+}
+
+// restoreCurrent points the current symlink of appName back at previous,
+// or removes it if previous is "", and links the executables of that
+// version as far as possible. It is used to undo a failed activation.
+func restoreCurrent(baseDir string, reg *linkRegistry, binDirs []string, appName, previous string, force bool) {
+	currentLink := filepath.Join(baseDir, appName, "current")
+	if previous == "" {
+		os.Remove(currentLink)
+	} else if err := replaceSymlink(previous, currentLink); err != nil {
+		return
+	}
+
+	executables, _ := appExecutables(baseDir, appName, "current")
+	reconcileLinks(baseDir, reg, binDirs, appName, executables, force)
+}
+
+// installOptions holds the optional flags of the install command.
+type installOptions struct {
+	sha256    string // expected sha256 of the source file or archive
+	checksums string // path or URL of a SHA256SUMS file
+	signature string // path or URL of a minisign signature (default: <path>.minisig)
+	force     bool   // take over bin links owned by other apps
+}
+
+// installPath installs srcPath as version of appName, dispatching on
+// whether it is an archive, a directory or a single binary. URLs are
+// downloaded into the cache first. Checksums requested in opts, and the
+// signature when the app has trusted keys, are verified before anything is
+// written to the version directory.
+func installPath(baseDir, srcPath, appName, version string, opts installOptions) error {
+	if err := validateName("app", appName); err != nil {
+		return err
+	}
+	if err := validateVersionName(version); err != nil {
+		return err
+	}
+	if err := cleanStaleStaging(baseDir); err != nil {
+		return fmt.Errorf("failed to clean staging directory: %w", err)
+	}
+
+	sigSrc := opts.signature
+	if sigSrc == "" {
+		sigSrc = srcPath + ".minisig"
+	}
+
+	name := filepath.Base(srcPath)
+	if isURL(srcPath) {
+		urlName, err := artifactName(srcPath)
+		if err != nil {
+			return err
+		}
+		name = urlName
+	}
+
+	expected, err := expectedSHA256(opts, name)
+	if err != nil {
+		return err
+	}
+
+	source := srcPath
+	if isURL(srcPath) {
+		cached, err := downloadArtifact(baseDir, srcPath, expected)
+		if err != nil {
+			return err
+		}
+		srcPath = cached
+	} else if abs, err := filepath.Abs(srcPath); err == nil {
+		source = abs
+	}
+
+	// Check if srcPath is a file or directory
+	srcInfo, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+
+	if expected != "" {
+		if srcInfo.IsDir() {
+			return fmt.Errorf("checksum verification requires a file or archive, not a directory")
+		}
+		if err := verifySHA256(srcPath, expected); err != nil {
+			return err
+		}
+	}
+
+	if srcInfo.IsDir() {
+		keys, err := loadTrustedKeys(baseDir, appName)
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 || opts.signature != "" {
+			return fmt.Errorf("%s requires a signed file or archive; folders cannot be verified", appName)
+		}
+	} else if err := verifyArtifactSignature(baseDir, appName, srcPath, sigSrc, opts.signature != ""); err != nil {
+		return err
+	}
+
+	sum := ""
+	if !srcInfo.IsDir() {
+		if sum, err = fileSHA256(srcPath); err != nil {
+			return err
 		}
 	}
 
-	// Create current symlink
-	if err := os.Symlink(version, currentLink); err != nil {
-		return fmt.Errorf("failed to create current symlink: %w", err)
+	switch {
+	case srcInfo.IsDir():
+		err = installDirectory(baseDir, srcPath, appName, version, opts.force)
+	case isArchive(srcPath):
+		err = installArchive(baseDir, srcPath, appName, version, opts.force)
+	default:
+		err = installBinary(baseDir, srcPath, appName, version, opts.force)
+	}
+	if err != nil {
+		return err
 	}
 
-	// Create symlinks in ~/.local/bin for all executables in bin/
-	if err := createBinSymlinks(baseDir, appName); err != nil {
-		return fmt.Errorf("failed to create bin symlinks: %w", err)
+	dir := filepath.Join(baseDir, appName, version)
+	m, err := buildManifest(dir)
+	if err == nil {
+		err = writeManifest(baseDir, appName, version, m)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record manifest: %w", err)
 	}
 
+	meta, err := newVersionMeta(dir, source, sum)
+	if err == nil {
+		err = writeVersionMeta(baseDir, appName, version, meta)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record install metadata: %w", err)
+	}
 	return nil
 }
 
+// parseArgs separates positional arguments from --flags, which may appear
+// anywhere after the command. flags maps each accepted flag name (without
+// dashes) to whether it takes a value; boolean flags are recorded as "true".
+// Everything after a "--" argument is treated as positional.
+func parseArgs(args []string, flags map[string]bool) ([]string, map[string]string, error) {
+	var positional []string
+	values := make(map[string]string)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		takesValue, ok := flags[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown flag: --%s", name)
+		}
+
+		switch {
+		case takesValue && !hasValue:
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("flag --%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		case !takesValue && hasValue:
+			return nil, nil, fmt.Errorf("flag --%s does not take a value", name)
+		case !takesValue:
+			value = "true"
+		}
+		values[name] = value
+	}
+
+	return positional, values, nil
+}
+
+// printVersionTable prints versions one per line with their install date,
+// size and source.
+func printVersionTable(versions []versionJSON) {
+	width := 0
+	for _, v := range versions {
+		width = max(width, len(v.Version))
+	}
+	for _, v := range versions {
+		marker := " "
+		if v.Current {
+			marker = "*"
+		}
+		source := "-"
+		if v.Meta != nil {
+			source = v.Meta.Source
+		}
+		fmt.Printf("%s %-*s  %s  %9s  %s\n", marker, width, v.Version, v.InstalledAt.Local().Format("2006-01-02"), formatSize(v.Size), source)
+	}
+}
+
+// printVerifyResult prints the outcome of verifying a version.
+func printVerifyResult(r verifiedJSON) {
+	title := r.App + " " + r.Version
+	switch r.Status {
+	case "ok":
+		fmt.Printf("%s: ok\n", title)
+	case "unverified":
+		fmt.Printf("%s: no manifest\n", title)
+	case "repaired":
+		fmt.Printf("%s: repaired\n", title)
+	default:
+		fmt.Printf("%s: damaged\n", title)
+	}
+	for _, issue := range r.Issues {
+		fmt.Printf("  %-9s %s\n", issue.Kind, issue.Path)
+	}
+	if r.Error != "" {
+		fmt.Printf("  cannot repair: %s\n", r.Error)
+	}
+}
+
+// printBinLink prints a link found by lav which and the file it runs.
+func printBinLink(info binLinkInfo) {
+	switch {
+	case info.app == "":
+		fmt.Printf("%s -> %s (not managed by lav)\n", info.link, info.target)
+	case info.version == "":
+		fmt.Printf("%s -> %s (owned by %s)\n", info.link, info.target, info.app)
+	case info.shim:
+		fmt.Printf("%s -> %s (shim of %s %s)\n", info.link, info.target, info.app, formatVersionSource(info.version, info.source))
+	default:
+		fmt.Printf("%s -> %s (owned by %s %s)\n", info.link, info.target, info.app, info.version)
+	}
+	switch {
+	case info.file != "":
+		fmt.Printf("  runs %s\n", info.file)
+	case info.app != "":
+		fmt.Println("  dangling; run 'lav doctor'")
+	}
+}
+
+// printOwner prints the owner of a path found by lav owner.
+func printOwner(o ownerJSON) {
+	owner := o.App
+	if o.Version != "" {
+		owner += " " + o.Version
+	}
+	if o.File != "" {
+		owner += " (" + o.File + ")"
+	}
+	if o.Link != "" {
+		fmt.Printf("%s: link of %s\n", o.Path, owner)
+	} else {
+		fmt.Printf("%s: %s\n", o.Path, owner)
+	}
+}
+
+// printDoctorFinding prints a finding of lav doctor and, when fixes were
+// applied, the outcome of its fix.
+func printDoctorFinding(f findingJSON, fixing bool) {
+	fmt.Printf("%s%s: %s\n", strings.ToUpper(f.Severity[:1]), f.Severity[1:], f.Message)
+	switch {
+	case f.Fixed:
+		fmt.Printf("  fixed: %s\n", f.Fix)
+	case f.FixError != "":
+		fmt.Printf("  could not %s: %s\n", f.Fix, f.FixError)
+	case f.Fix != "" && !fixing:
+		fmt.Printf("  fix: %s\n", f.Fix)
+	}
+}
+
+// printVersionInfo prints the description of a version shown by lav info.
+func printVersionInfo(v versionJSON) {
+	title := v.App + " " + v.Version
+	if v.Current {
+		title += " (current)"
+	}
+	fmt.Println(title)
+	fmt.Printf("  Path:          %s\n", v.Path)
+	fmt.Printf("  Size:          %s\n", formatSize(v.Size))
+	if v.Meta == nil {
+		fmt.Println("  No install metadata recorded; installed by an older lav")
+		return
+	}
+	fmt.Printf("  Source:        %s\n", v.Meta.Source)
+	if v.Meta.SHA256 != "" {
+		fmt.Printf("  SHA256:        %s\n", v.Meta.SHA256)
+	}
+	fmt.Printf("  Installed:     %s by lav %s\n", v.Meta.InstalledAt.Local().Format("2006-01-02 15:04:05 MST"), v.Meta.LavVersion)
+	if v.Meta.Arch != "" {
+		fmt.Printf("  Architecture:  %s\n", v.Meta.Arch)
+	}
+	fmt.Printf("  Files:         %d (%s at install)\n", v.Meta.Files, formatSize(v.Meta.Size))
+}
+
+// formatVersionSource formats a version resolved by resolveActiveVersion,
+// noting where it is set unless it is the global current version.
+func formatVersionSource(version, source string) string {
+	if source == "current" {
+		return version
+	}
+	return fmt.Sprintf("%s (set by %s)", version, source)
+}
+
+// printPathHint tells the user how to fix PATH when a bin directory is
+// missing from it.
+func printPathHint() {
+	binDirs, err := getBinDirs()
+	if err != nil {
+		return
+	}
+	if hint := pathHint(binDirs, os.Getenv("PATH")); hint != "" {
+		fmt.Fprintln(os.Stderr, hint)
+	}
+}
+
+// printOutputOptions prints the options of commands that support
+// machine-readable output.
+func printOutputOptions() {
+	fmt.Println("  --json                Print a JSON document instead of text")
+	fmt.Println("  --format <template>   Print each record with a Go template, e.g. '{{.version}}'")
+}
+
 func printUsage() {
 	fmt.Println("Usage:")
-	fmt.Println("  lav install <path> <app> <version>  Install a binary or folder")
+	fmt.Println("  lav install <path> <app> <version>  Install a binary, folder, archive or URL")
 	fmt.Println("  lav use <app> [version]             Switch to a specific version")
 	fmt.Println("  lav list [app]                      List all apps or versions for a specific app")
 	fmt.Println("  lav current [app]                   Show current version for an app or all apps")
+	fmt.Println("  lav info <app> [version]            Show where a version came from")
+	fmt.Println("  lav verify [app] [version]          Check installed files against their manifest")
+	fmt.Println("  lav local [app] [version]           Set or show the versions a project expects")
+	fmt.Println("  lav exec <app>@<version> -- <cmd>   Run a command with specific versions")
+	fmt.Println("  lav shell <app> <version>           Start a shell with a specific version")
+	fmt.Println("  lav remove <app> <version>|--all    Remove a version or a whole app")
+	fmt.Println("  lav prune [app]                     Remove old versions by retention policy")
+	fmt.Println("  lav relink                          Rewrite bin links after the lav root moved")
+	fmt.Println("  lav which <executable>              Show which app, version and file a link runs")
+	fmt.Println("  lav owner <path>                    Show which app and version own a file")
+	fmt.Println("  lav alternatives <executable>       Choose between apps providing an executable")
+	fmt.Println("  lav trust <add|list|remove> <app>   Manage trusted signing keys")
+	fmt.Println("  lav doctor [--fix]                  Check the setup for problems")
+	fmt.Println("  lav init <bash|zsh|fish>            Print shell setup: PATH and completions")
 	fmt.Println("  lav --version, -v                   Show version information")
 	fmt.Println("  lav --help, -h, help                Show this help message")
 	fmt.Println()
+	fmt.Println("Global options:")
+	fmt.Println("  --bin-dir <dir>   Link executables into <dir> instead of ~/.local/bin")
+	fmt.Println("                    (repeatable; overrides LAV_BIN_DIR and bin_dir in the config)")
+	fmt.Println()
 	fmt.Println("Use 'lav <command> --help' for more information about a command.")
 }
 
 func printInstallHelp() {
-	fmt.Println("Usage: lav install <path> <app> <version>")
+	fmt.Println("Usage: lav install <path> <app> <version> [options]")
 	fmt.Println()
-	fmt.Println("Install a binary or folder to the apps structure.")
+	fmt.Println("Install a binary, folder or archive to the apps structure.")
+	fmt.Println("Archives (.tar.gz, .tar.xz, .zip) are extracted directly; a single")
+	fmt.Println("top-level folder in the archive is stripped.")
+	fmt.Println("HTTP(S) URLs are downloaded into the cache and reused on reinstall.")
 	fmt.Println()
 	fmt.Println("Arguments:")
-	fmt.Println("  <path>     Path to a binary file or folder containing bin/")
+	fmt.Println("  <path>     Path to a binary file, folder containing bin/, archive, or URL")
 	fmt.Println("  <app>      Application name")
 	fmt.Println("  <version>  Version string (e.g., 1.0.0)")
 	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --sha256 <hex>        Verify the file or archive against a sha256 checksum")
+	fmt.Println("  --checksums <file>    Verify against a SHA256SUMS file (path or URL)")
+	fmt.Println("  --signature <file>    Minisign signature to verify (default: <path>.minisig)")
+	fmt.Println("  --force               Take over bin links owned by other apps")
+	printOutputOptions()
+	fmt.Println()
+	fmt.Println("If the app has trusted keys (see 'lav trust'), the artifact must carry a")
+	fmt.Println("valid signature from one of them.")
+	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  lav install ./lav lav 0.0.0")
 	fmt.Println("  lav install ~/Downloads/go1.25.6.linux-amd64/go go 1.25.6")
+	fmt.Println("  lav install ~/Downloads/go1.25.6.linux-amd64.tar.gz go 1.25.6")
+	fmt.Println("  lav install https://go.dev/dl/go1.25.6.linux-amd64.tar.gz go 1.25.6")
+	fmt.Println("  lav install ./app.tar.gz app 1.0.0 --checksums ./SHA256SUMS")
 }
 
 func printUseHelp() {
-	fmt.Println("Usage: lav use <app> [version]")
+	fmt.Println("Usage: lav use <app> [version] [--pre] [--force]")
 	fmt.Println()
 	fmt.Println("Switch to a specific version of an installed application.")
 	fmt.Println("If version is omitted, shows an interactive version selector.")
 	fmt.Println()
+	fmt.Println("The version can be an installed version name, \"latest\", or a constraint")
+	fmt.Println("resolved to the highest matching installed version:")
+	fmt.Println("  1.23              Any 1.23.x")
+	fmt.Println("  ^4.5              >=4.5.0 <5.0.0")
+	fmt.Println("  ~1.22.3           >=1.22.3 <1.23.0")
+	fmt.Println("  \">=1.21, <1.23\"   Comparisons (>, >=, <, <=, =, !=)")
+	fmt.Println()
 	fmt.Println("Arguments:")
 	fmt.Println("  <app>      Application name")
-	fmt.Println("  [version]  Version to switch to (optional)")
+	fmt.Println("  [version]  Version or constraint to switch to (optional)")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --pre      Include pre-releases when resolving latest or a constraint")
+	fmt.Println("  --force    Take over bin links owned by other apps")
+	printOutputOptions()
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  lav use go 1.25.6    # Switch to specific version")
+	fmt.Println("  lav use go latest    # Switch to the newest release")
+	fmt.Println("  lav use godot ^4.5   # Switch to the newest 4.x from 4.5 on")
 	fmt.Println("  lav use go           # Interactive version selection")
 }
 
 func printListHelp() {
-	fmt.Println("Usage: lav list [app]")
+	fmt.Println("Usage: lav list [app] [--long] [--json|--format <template>]")
 	fmt.Println()
 	fmt.Println("List all installed applications or versions for a specific application.")
 	fmt.Println()
 	fmt.Println("Arguments:")
 	fmt.Println("  [app]  Optional application name to list versions for")
 	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --long                Show each version's install date, size and source")
+	printOutputOptions()
+	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  lav list         # List all applications")
 	fmt.Println("  lav list go      # List versions of go")
+	fmt.Println("  lav list go --long")
 }
 
-func printCurrentHelp() {
-	fmt.Println("Usage: lav current [app]")
+func printInfoHelp() {
+	fmt.Println("Usage: lav info <app> [version]")
 	fmt.Println()
-	fmt.Println("Show the current version for an application or all applications.")
+	fmt.Println("Show the install metadata of a version (the current version if omitted):")
+	fmt.Println("the path or URL it was installed from, the sha256 of the artifact, when")
+	fmt.Println("and by which lav it was installed, the detected architecture, and the")
+	fmt.Println("number and size of its files.")
 	fmt.Println()
-	fmt.Println("Arguments:")
-	fmt.Println("  [app]  Optional application name")
+	fmt.Println("Options:")
+	printOutputOptions()
 	fmt.Println()
 	fmt.Println("Examples:")
-	fmt.Println("  lav current      # Show current versions of all apps")
-	fmt.Println("  lav current go   # Show current version of go")
+	fmt.Println("  lav info godot          # The current version of godot")
+	fmt.Println("  lav info godot 4.5.1")
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		printUsage()
-		os.Exit(1)
-	}
-
+func printVerifyHelp() {
+	fmt.Println("Usage: lav verify [app] [version] [--repair]")
+	fmt.Println()
+	fmt.Println("Check installed versions against the manifest recorded at install time")
+	fmt.Println("and report files that were modified, changed mode, went missing or were")
+	fmt.Println("added. Without arguments every installed version is checked; with only")
+	fmt.Println("an app, every version of that app.")
+	fmt.Println()
+	fmt.Println("Versions installed before lav recorded manifests cannot be verified.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --repair              Restore damaged versions from the cached download or")
+	fmt.Println("                        the original file or folder, if it is unchanged")
+	printOutputOptions()
+	fmt.Println()
+	fmt.Println("Exits with status 1 if any problems remain.")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  lav verify               # Check everything")
+	fmt.Println("  lav verify go 1.25.6")
+	fmt.Println("  lav verify go --repair")
+}
+
+func printCurrentHelp() {
+	fmt.Println("Usage: lav current [app] [--json|--format <template>]")
+	fmt.Println()
+	fmt.Println("Show the version in effect for an application or all applications, and")
+	fmt.Println("where it is set when that is not the global current version: the")
+	fmt.Println("LAV_<APP>_VERSION environment variable, a lav shell session or a project")
+	fmt.Println("version file.")
+	fmt.Println()
+	fmt.Println("Arguments:")
+	fmt.Println("  [app]  Optional application name")
+	fmt.Println()
+	fmt.Println("Options:")
+	printOutputOptions()
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  lav current      # Show current versions of all apps")
+	fmt.Println("  lav current go   # Show current version of go")
+}
+
+func printLocalHelp() {
+	fmt.Println("Usage: lav local [app] [version]")
+	fmt.Println()
+	fmt.Println("Set or show the versions a project expects. With a version, sets it in")
+	fmt.Println("the project version file in the working directory (.lav.toml, or")
+	fmt.Println(".lav-version if only that exists), creating .lav.toml if needed.")
+	fmt.Println("Without one, shows the versions set by the project version files in the")
+	fmt.Println("working directory and its parents.")
+	fmt.Println()
+	fmt.Println("The version may be a constraint such as 1.23 or ^4.5. Project versions")
+	fmt.Println("apply to apps in shim mode and are shown by 'lav current'.")
+	fmt.Println()
+	fmt.Println("Options:")
+	printOutputOptions()
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  lav local go 1.23.4   # Pin go for this project")
+	fmt.Println("  lav local             # Show the project's versions")
+}
+
+func printExecHelp() {
+	fmt.Println("Usage: lav exec <app>[@version]... -- <command> [args...]")
+	fmt.Println()
+	fmt.Println("Run a command with the bin directories of the given versions prepended to")
+	fmt.Println("PATH, without changing the current versions. Each version may be \"latest\"")
+	fmt.Println("or a constraint; without one, the version in effect in the working")
+	fmt.Println("directory is used. LAV_<APP>_VERSION is set so that shims agree, along")
+	fmt.Println("with the variables in the app's [apps.<name>.env] config table.")
+	fmt.Println()
+	fmt.Println("Alias: lav run")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  lav exec go@1.22.0 -- go test ./...")
+	fmt.Println("  lav exec go@1.23 node@22 -- make")
+}
+
+func printShellHelp() {
+	fmt.Println("Usage: lav shell <app> <version>")
+	fmt.Println("       lav shell <app>[@version]...")
+	fmt.Println("       lav shell --export [--shell <name>] <app>[@version]...")
+	fmt.Println("       lav shell --unset [--shell <name>] <app>...")
+	fmt.Println()
+	fmt.Println("Start a subshell in which the given versions take precedence over the")
+	fmt.Println("current versions, as with 'lav exec'. Exit the subshell to return. Inside")
+	fmt.Println("the session, 'lav current' reports the version as set by lav shell.")
+	fmt.Println()
+	fmt.Println("With --export, print the commands that apply the versions to the current")
+	fmt.Println("shell instead, for use with eval; --unset prints the commands that undo")
+	fmt.Println("them. The shell syntax follows $SHELL unless --shell is given (bash, zsh,")
+	fmt.Println("fish or sh).")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --export        Print commands that set the versions in the current shell")
+	fmt.Println("  --unset         Print commands that remove the versions again")
+	fmt.Println("  --shell <name>  Shell syntax to print")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  lav shell go 1.22.0")
+	fmt.Println("  lav shell go@1.23 node@22")
+	fmt.Println("  eval \"$(lav shell --export go 1.22.0)\"")
+	fmt.Println("  eval \"$(lav shell --unset go)\"")
+}
+
+func printRemoveHelp() {
+	fmt.Println("Usage: lav remove <app> <version> [--force]")
+	fmt.Println("       lav remove <app> --all [--force]")
+	fmt.Println()
+	fmt.Println("Remove an installed version, or every version of an app. The current")
+	fmt.Println("version is only removed with --force; its current symlink and the app's")
+	fmt.Println("links in the bin directories are removed with it.")
+	fmt.Println()
+	fmt.Println("Alias: lav uninstall")
+	fmt.Println()
+	fmt.Println("Arguments:")
+	fmt.Println("  <app>      Application name")
+	fmt.Println("  <version>  Version to remove")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --all      Remove all versions of the app")
+	fmt.Println("  --force    Allow removing the current version")
+	printOutputOptions()
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  lav remove go 1.22.0        # Remove a non-current version")
+	fmt.Println("  lav remove go --all --force # Remove go entirely")
+}
+
+func printPruneHelp() {
+	fmt.Println("Usage: lav prune [app] [--keep N] [--older-than AGE] [--apply] [--json|--format <template>]")
+	fmt.Println()
+	fmt.Println("Remove old versions according to a retention policy. Without --apply,")
+	fmt.Println("only shows what would be removed and how much space it would reclaim.")
+	fmt.Println()
+	fmt.Println("The current version, versions pinned in the config and versions")
+	fmt.Println("referenced by project version files (.lav.toml, .lav-version) are never")
+	fmt.Println("removed. Without an app, every app with a retention policy is pruned.")
+	fmt.Println()
+	fmt.Println("Arguments:")
+	fmt.Println("  [app]  Optional application name")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --keep N            Keep the N newest versions")
+	fmt.Println("  --older-than AGE    Only remove versions older than AGE (e.g. 90d, 2w, 36h)")
+	fmt.Println("  --apply             Remove the versions instead of showing them")
+	printOutputOptions()
+	fmt.Println()
+	fmt.Println("Per-app policies can be set in the config file:")
+	fmt.Println("  [apps.godot]")
+	fmt.Println("  keep = 3")
+	fmt.Println("  older_than = \"90d\"")
+	fmt.Println("  pinned = [\"4.2.2\"]")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  lav prune godot --keep 3            # Show what would be removed")
+	fmt.Println("  lav prune godot --keep 3 --apply    # Remove it")
+	fmt.Println("  lav prune --older-than 90d")
+	fmt.Println("  lav prune --json                    # List the candidates with sizes and reasons")
+}
+
+func printRelinkHelp() {
+	fmt.Println("Usage: lav relink [--force]")
+	fmt.Println()
+	fmt.Println("Rewrite the links in the bin directories for every app with a current")
+	fmt.Println("version so that they point into the current lav root. Run this after")
+	fmt.Println("moving the root (LAV_ROOT or XDG_DATA_HOME) to a new location.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --force    Also replace links that lav does not recognize as its own")
+}
+
+func printWhichHelp() {
+	fmt.Println("Usage: lav which <executable> [--json|--format <template>]")
+	fmt.Println()
+	fmt.Println("Show the link for an executable in each bin directory, the app that")
+	fmt.Println("owns it, and the file it runs: through the app's current symlink or, in")
+	fmt.Println("shim mode, in the version picked in the working directory. Warns when")
+	fmt.Println("another executable of the same name comes first on PATH.")
+	fmt.Println()
+	fmt.Println("Options:")
+	printOutputOptions()
+}
+
+func printOwnerHelp() {
+	fmt.Println("Usage: lav owner <path>... [--json|--format <template>]")
+	fmt.Println()
+	fmt.Println("Show the app and version that own a path: a file under the lav root,")
+	fmt.Println("a symlink into it, or a link in a bin directory. Paths through an app's")
+	fmt.Println("current symlink are attributed to the version it points at; bin links")
+	fmt.Println("in shim mode to the version picked in the working directory.")
+	fmt.Println()
+	fmt.Println("Options:")
+	printOutputOptions()
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  lav owner ~/.local/bin/gofmt")
+	fmt.Println("  lav owner ~/.local/share/lav/go/current/pkg/tool")
+}
+
+func printAlternativesHelp() {
+	fmt.Println("Usage: lav alternatives <executable> [app] [options]")
+	fmt.Println()
+	fmt.Println("Manage apps that provide the same executable. In auto mode the")
+	fmt.Println("available provider with the highest priority owns the links; naming an")
+	fmt.Println("app makes it the active provider for as long as it is available. When")
+	fmt.Println("the active provider is removed, the next one takes over.")
+	fmt.Println()
+	fmt.Println("Without options, lists the providers; * marks the active one.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --add <app>         Register app as a provider")
+	fmt.Println("  --priority <n>      Priority of the provider being added (default: 0)")
+	fmt.Println("  --remove <app>      Unregister a provider")
+	fmt.Println("  --auto              Return to auto mode")
+	fmt.Println("  --force             Replace links not owned by any provider")
+	printOutputOptions()
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  lav alternatives godot --add godot --priority 10")
+	fmt.Println("  lav alternatives godot --add godot-mono --priority 20")
+	fmt.Println("  lav alternatives godot godot        # Use the standard build")
+	fmt.Println("  lav alternatives godot --auto")
+}
+
+func printDoctorHelp() {
+	fmt.Println("Usage: lav doctor [--fix] [--json|--format <template>]")
+	fmt.Println()
+	fmt.Println("Check the lav tree and setup for problems:")
+	fmt.Println("  - current entries that are directories instead of symlinks")
+	fmt.Println("  - current symlinks and bin links that are dangling")
+	fmt.Println("  - bin links pointing outside the lav root, e.g. after it moved")
+	fmt.Println("  - versions without a bin/ directory, and non-executable files in bin/")
+	fmt.Println("  - bin directories that are not on PATH")
+	fmt.Println("  - staging directories left behind by interrupted installs")
+	fmt.Println()
+	fmt.Println("Each finding is an error, a warning or a note. Exits with status 1 if")
+	fmt.Println("any error remains.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --fix                 Apply the suggested fixes")
+	printOutputOptions()
+}
+
+func printInitHelp() {
+	fmt.Println("Usage: lav init <bash|zsh|fish> [--hook]")
+	fmt.Println()
+	fmt.Println("Print shell code that adds the bin directories to PATH and sets up")
+	fmt.Println("completion of commands, apps and versions. Load it from your shell's")
+	fmt.Println("startup file.")
+	fmt.Println()
+	fmt.Println("With --hook, the code also applies the versions in project version files")
+	fmt.Println("whenever the working directory changes, and makes 'lav shell <app>")
+	fmt.Println("<version>' change the current shell instead of starting a subshell.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --hook   Apply project versions on directory change")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  eval \"$(lav init bash)\"          # in ~/.bashrc")
+	fmt.Println("  eval \"$(lav init zsh --hook)\"    # in ~/.zshrc")
+	fmt.Println("  lav init fish | source            # in ~/.config/fish/config.fish")
+}
+
+func printTrustHelp() {
+	fmt.Println("Usage: lav trust <subcommand> <app> [args]")
+	fmt.Println()
+	fmt.Println("Manage the minisign public keys trusted to sign artifacts of an app.")
+	fmt.Println("Once an app has trusted keys, 'lav install' refuses unsigned or badly")
+	fmt.Println("signed artifacts for it.")
+	fmt.Println()
+	fmt.Println("Subcommands:")
+	fmt.Println("  add <app> <pubkey>       Trust a public key (base64 key or .pub file)")
+	fmt.Println("  list [app]               List trusted keys")
+	fmt.Println("  remove <app> <key-id>    Stop trusting a key")
+	fmt.Println()
+	fmt.Println("Options for list:")
+	printOutputOptions()
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  lav trust add go RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3")
+	fmt.Println("  lav trust add go ./minisign.pub")
+	fmt.Println("  lav trust list go")
+}
+
+func main() {
+	// Invoked through a shim: run the app's executable instead
+	if name := filepath.Base(os.Args[0]); strings.TrimSuffix(name, ".exe") != "lav" {
+		if baseDir, err := getBaseDir(); err == nil {
+			if app := shimApp(baseDir, name); app != "" {
+				err := runShim(baseDir, app, name, os.Args[1:])
+				fmt.Fprintf(os.Stderr, "lav: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	args, err := extractBinDirFlag(os.Args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Args = args
+
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
 	command := os.Args[1]
 
 	// Handle help flag
@@ -465,37 +1192,40 @@ func main() {
 			return
 		}
 
-		if len(os.Args) != 5 {
-			fmt.Fprintln(os.Stderr, "Usage: lav install <path> <app> <version>")
+		args, flags, err := parseArgs(os.Args[2:], withOutputFlags(map[string]bool{"sha256": true, "checksums": true, "signature": true, "force": false}))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-
-		srcPath := os.Args[2]
-		appName := os.Args[3]
-		version := os.Args[4]
-
-		// Check if srcPath is a file or directory
-		srcInfo, err := os.Stat(srcPath)
+		format, err := parseOutputFormat(flags)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		if srcInfo.IsDir() {
-			// Install directory
-			if err := installDirectory(baseDir, srcPath, appName, version); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-		} else {
-			// Install binary
-			if err := installBinary(baseDir, srcPath, appName, version); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, "Usage: lav install <path> <app> <version> [options]")
+			os.Exit(1)
+		}
+
+		srcPath := args[0]
+		appName := args[1]
+		version := args[2]
+		opts := installOptions{sha256: flags["sha256"], checksums: flags["checksums"], signature: flags["signature"], force: flags["force"] != ""}
+
+		previous, _ := getCurrentVersion(baseDir, appName)
+		if err := installPath(baseDir, srcPath, appName, version, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
 
+		if !format.isText() {
+			change := describeChange(baseDir, "install", appName, version, previous)
+			printOutput(format, change, change)
+			return
+		}
 		fmt.Printf("Installed %s version %s\n", appName, version)
+		printPathHint()
 
 	case "use":
 		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
@@ -503,9 +1233,20 @@ func main() {
 			return
 		}
 
-		if len(os.Args) == 3 {
+		args, flags, err := parseArgs(os.Args[2:], withOutputFlags(map[string]bool{"pre": false, "force": false}))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(args) == 1 {
 			// インタラクティブモード
-			app := os.Args[2]
+			app := args[0]
 			versions, err := listVersions(baseDir, app)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -517,7 +1258,13 @@ func main() {
 			}
 			current, _ := getCurrentVersion(baseDir, app)
 
-			selected, cancelled, err := selectVersionInteractive(app, versions, current)
+			details := make(map[string]string)
+			for _, v := range versions {
+				meta, _ := readVersionMeta(baseDir, app, v)
+				details[v] = formatMetaSummary(meta)
+			}
+
+			selected, cancelled, err := selectVersionInteractive(app, versions, current, details)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
@@ -526,24 +1273,46 @@ func main() {
 				return
 			}
 
-			if err := switchVersion(baseDir, app, selected); err != nil {
+			if err := switchVersion(baseDir, app, selected, flags["force"] != ""); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
+			if !format.isText() {
+				change := describeChange(baseDir, "use", app, selected, current)
+				printOutput(format, change, change)
+				return
+			}
 			fmt.Printf("Switched %s to version %s\n", app, selected)
+			printPathHint()
 
-		} else if len(os.Args) == 4 {
-			// 従来モード
-			app := os.Args[2]
-			version := os.Args[3]
-			if err := switchVersion(baseDir, app, version); err != nil {
+		} else if len(args) == 2 {
+			// 従来モード (exact version, "latest" or a constraint)
+			app := args[0]
+			versions, err := listVersions(baseDir, app)
+			if err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			version, err := resolveVersion(app, versions, args[1], flags["pre"] != "")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			previous, _ := getCurrentVersion(baseDir, app)
+			if err := switchVersion(baseDir, app, version, flags["force"] != ""); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
+			if !format.isText() {
+				change := describeChange(baseDir, "use", app, version, previous)
+				printOutput(format, change, change)
+				return
+			}
 			fmt.Printf("Switched %s to version %s\n", app, version)
+			printPathHint()
 
 		} else {
-			fmt.Fprintln(os.Stderr, "Usage: lav use <app> [version]")
+			fmt.Fprintln(os.Stderr, "Usage: lav use <app> [version] [--pre] [--force]")
 			os.Exit(1)
 		}
 
@@ -554,13 +1323,55 @@ func main() {
 			return
 		}
 
-		if len(os.Args) == 2 {
+		args, flags, err := parseArgs(os.Args[2:], withOutputFlags(map[string]bool{"long": false}))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		_, long := flags["long"]
+
+		if len(args) == 0 {
 			apps, err := listApps(baseDir)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 
+			if !format.isText() {
+				doc := listJSON{jsonHeader: newJSONHeader(), Apps: []appJSON{}}
+				for _, app := range apps {
+					desc, err := describeApp(baseDir, app)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+						os.Exit(1)
+					}
+					doc.Apps = append(doc.Apps, desc)
+				}
+				printOutput(format, doc, doc.Apps)
+				return
+			}
+
+			if long {
+				for i, app := range apps {
+					desc, err := describeApp(baseDir, app)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+						os.Exit(1)
+					}
+					if i > 0 {
+						fmt.Println()
+					}
+					fmt.Printf("%s:\n", app)
+					printVersionTable(desc.Versions)
+				}
+				return
+			}
+
 			for _, app := range apps {
 				current, _ := getCurrentVersion(baseDir, app)
 				if current != "" {
@@ -569,8 +1380,28 @@ func main() {
 					fmt.Println(app)
 				}
 			}
-		} else if len(os.Args) == 3 {
-			app := os.Args[2]
+		} else if len(args) == 1 {
+			app := args[0]
+			if !format.isText() {
+				desc, err := describeApp(baseDir, app)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				printOutput(format, appVersionsJSON{jsonHeader: newJSONHeader(), appJSON: desc}, desc.Versions)
+				return
+			}
+
+			if long {
+				desc, err := describeApp(baseDir, app)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				printVersionTable(desc.Versions)
+				return
+			}
+
 			versions, err := listVersions(baseDir, app)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -586,46 +1417,874 @@ func main() {
 				}
 			}
 		} else {
-			fmt.Fprintln(os.Stderr, "Usage: lav list [app]")
+			fmt.Fprintln(os.Stderr, "Usage: lav list [app] [--long] [--json|--format <template>]")
 			os.Exit(1)
 		}
 
-	case "current":
-		// Check for help flag
+	case "info":
 		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
-			printCurrentHelp()
+			printInfoHelp()
 			return
 		}
 
-		if len(os.Args) == 2 {
-			apps, err := listApps(baseDir)
-			if err != nil {
+		args, flags, err := parseArgs(os.Args[2:], outputFlags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 || len(args) > 2 {
+			fmt.Fprintln(os.Stderr, "Usage: lav info <app> [version] [--json|--format <template>]")
+			os.Exit(1)
+		}
+
+		app := args[0]
+		current, _ := getCurrentVersion(baseDir, app)
+		version := current
+		if len(args) == 2 {
+			versions, err := listVersions(baseDir, app)
+			if err != nil && !os.IsNotExist(err) {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
-
-			for _, app := range apps {
-				current, _ := getCurrentVersion(baseDir, app)
-				if current != "" {
-					fmt.Printf("%s: %s\n", app, current)
-				}
-			}
-		} else if len(os.Args) == 3 {
-			app := os.Args[2]
-			current, err := getCurrentVersion(baseDir, app)
-			if err != nil {
+			if version, err = resolveVersion(app, versions, args[1], true); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
+		} else if version == "" {
+			fmt.Fprintf(os.Stderr, "Error: no version of %s is selected\n", app)
+			os.Exit(1)
+		}
 
-			if current != "" {
-				fmt.Println(current)
-			} else {
-				fmt.Fprintf(os.Stderr, "No current version set for %s\n", app)
+		desc, err := describeVersion(baseDir, app, version, current)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !format.isText() {
+			printOutput(format, infoJSON{jsonHeader: newJSONHeader(), versionJSON: desc}, desc)
+			return
+		}
+		printVersionInfo(desc)
+
+	case "verify":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printVerifyHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], withOutputFlags(map[string]bool{"repair": false}))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) > 2 {
+			fmt.Fprintln(os.Stderr, "Usage: lav verify [app] [version] [--repair] [--json|--format <template>]")
+			os.Exit(1)
+		}
+		_, repair := flags["repair"]
+
+		targets, err := verifyTargets(baseDir, args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		doc := verifyJSON{jsonHeader: newJSONHeader(), Versions: []verifiedJSON{}}
+		failed := false
+		for _, target := range targets {
+			result, err := verifyTarget(baseDir, target.App, target.Version, repair)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
-		} else {
-			fmt.Fprintln(os.Stderr, "Usage: lav current [app]")
+			if result.Status == "failed" {
+				failed = true
+			}
+			doc.Versions = append(doc.Versions, result)
+			if format.isText() {
+				printVerifyResult(result)
+			}
+		}
+		if !format.isText() {
+			printOutput(format, doc, doc.Versions)
+		}
+		if failed {
+			os.Exit(1)
+		}
+
+	case "current":
+		// Check for help flag
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printCurrentHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], outputFlags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		doc := currentListJSON{jsonHeader: newJSONHeader(), Current: []currentJSON{}}
+		if len(args) == 0 {
+			apps, err := listApps(baseDir)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			for _, app := range apps {
+				version, source, err := resolveActiveVersion(baseDir, app, cwd)
+				if err != nil {
+					if current, _ := getCurrentVersion(baseDir, app); current != "" {
+						fmt.Fprintf(os.Stderr, "%s: %v\n", app, err)
+					}
+					continue
+				}
+				if format.isText() {
+					fmt.Printf("%s: %s\n", app, formatVersionSource(version, source))
+				}
+				doc.Current = append(doc.Current, currentJSON{App: app, Version: version, Source: source, Path: filepath.Join(baseDir, app, version)})
+			}
+		} else if len(args) == 1 {
+			app := args[0]
+			version, source, err := resolveActiveVersion(baseDir, app, cwd)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if format.isText() {
+				fmt.Println(formatVersionSource(version, source))
+			}
+			doc.Current = append(doc.Current, currentJSON{App: app, Version: version, Source: source, Path: filepath.Join(baseDir, app, version)})
+		} else {
+			fmt.Fprintln(os.Stderr, "Usage: lav current [app] [--json|--format <template>]")
+			os.Exit(1)
+		}
+		if !format.isText() {
+			printOutput(format, doc, doc.Current)
+		}
+
+	case "local":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printLocalHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], outputFlags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		switch len(args) {
+		case 0, 1:
+			versions, err := projectVersions(cwd)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			apps := slices.Sorted(maps.Keys(versions))
+			if len(args) == 1 {
+				apps = []string{args[0]}
+				if _, ok := versions[args[0]]; !ok {
+					fmt.Fprintf(os.Stderr, "No project version set for %s\n", args[0])
+					os.Exit(1)
+				}
+			}
+
+			if !format.isText() {
+				doc := localJSON{jsonHeader: newJSONHeader(), Versions: []projectVersionJSON{}}
+				for _, app := range apps {
+					doc.Versions = append(doc.Versions, projectVersionJSON{App: app, Version: versions[app].version, File: versions[app].file})
+				}
+				printOutput(format, doc, doc.Versions)
+				return
+			}
+			for _, app := range apps {
+				fmt.Printf("%s: %s (%s)\n", app, versions[app].version, versions[app].file)
+			}
+
+		case 2:
+			if !format.isText() {
+				fmt.Fprintln(os.Stderr, "Error: --json and --format only apply when showing versions")
+				os.Exit(1)
+			}
+			app, spec := args[0], args[1]
+			if err := validateName("app", app); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			versions, _ := listVersions(baseDir, app)
+			if _, err := resolveVersion(app, versions, spec, false); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+
+			path, err := setProjectVersion(cwd, app, spec)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Set %s to %s in %s\n", app, spec, path)
+
+		default:
+			fmt.Fprintln(os.Stderr, "Usage: lav local [app] [version]")
+			os.Exit(1)
+		}
+
+	case "exec", "run":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printExecHelp()
+			return
+		}
+
+		sep := slices.Index(os.Args, "--")
+		if sep < 3 || sep == len(os.Args)-1 {
+			fmt.Fprintln(os.Stderr, "Usage: lav exec <app>[@version]... -- <command> [args...]")
+			os.Exit(1)
+		}
+
+		err := runExec(baseDir, os.Args[2:sep], os.Args[sep+1:])
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+
+	case "shell":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printShellHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], map[string]bool{"export": false, "unset": false, "shell": true})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "Usage: lav shell <app> <version>")
+			os.Exit(1)
+		}
+		shell := flags["shell"]
+		if shell == "" {
+			shell = detectShell()
+		}
+
+		if _, ok := flags["unset"]; ok {
+			for _, app := range args {
+				if err := validateName("app", app); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, line := range shellUnsets(baseDir, args, os.Environ(), cfg, shell) {
+				fmt.Println(line)
+			}
+			return
+		}
+
+		selections, err := resolveExecSpecs(baseDir, shellSpecs(args))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if _, ok := flags["export"]; ok {
+			cfg, err := loadConfig()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, line := range shellExports(baseDir, selections, os.Environ(), cfg, shell) {
+				fmt.Println(line)
+			}
+			return
+		}
+
+		err = runShell(baseDir, selections)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+
+	case "remove", "uninstall":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printRemoveHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], withOutputFlags(map[string]bool{"all": false, "force": false}))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		all := flags["all"] != ""
+		force := flags["force"] != ""
+
+		var removed []string
+		var previous, version string
+		switch {
+		case all && len(args) == 1:
+			previous, _ = getCurrentVersion(baseDir, args[0])
+			removed, err = removeApp(baseDir, args[0], force)
+		case !all && len(args) == 2:
+			previous, _ = getCurrentVersion(baseDir, args[0])
+			version = args[1]
+			removed, err = removeVersion(baseDir, args[0], version, force)
+		default:
+			fmt.Fprintln(os.Stderr, "Usage: lav remove <app> <version>|--all [--force]")
+			os.Exit(1)
+		}
+
+		if err == nil && !format.isText() {
+			change := describeChange(baseDir, "remove", args[0], version, previous)
+			change.RemovedLinks = removed
+			printOutput(format, change, change)
+			return
+		}
+		for _, link := range removed {
+			// Links of shared executables are handed to the next provider
+			if _, err := os.Lstat(link); err == nil {
+				fmt.Printf("Updated link %s\n", link)
+			} else {
+				fmt.Printf("Removed link %s\n", link)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if all {
+			fmt.Printf("Removed %s\n", args[0])
+		} else {
+			fmt.Printf("Removed %s version %s\n", args[0], args[1])
+		}
+
+	case "prune":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printPruneHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], withOutputFlags(map[string]bool{"keep": true, "older-than": true, "apply": false}))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, "Usage: lav prune [app] [--keep N] [--older-than AGE] [--apply] [--json|--format <template>]")
+			os.Exit(1)
+		}
+
+		var policy prunePolicy
+		if keep, ok := flags["keep"]; ok {
+			if policy.keep, err = strconv.Atoi(keep); err != nil || policy.keep < 1 {
+				fmt.Fprintf(os.Stderr, "Error: --keep must be a positive integer\n")
+				os.Exit(1)
+			}
+		}
+		if olderThan, ok := flags["older-than"]; ok {
+			if policy.olderThan, err = parseAge(olderThan); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		apps := args
+		if len(apps) == 0 {
+			if apps, err = listApps(baseDir); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		candidates, err := planPruneApps(baseDir, apps, len(args) == 1, policy, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		apply := flags["apply"] != ""
+		doc := pruneJSON{jsonHeader: newJSONHeader(), Applied: apply, Candidates: []pruneCandidateJSON{}}
+		for _, c := range candidates {
+			if apply {
+				if _, err := removeVersion(baseDir, c.app, c.version, false); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				if format.isText() {
+					fmt.Printf("Removed %s %s (%s)\n", c.app, c.version, formatSize(c.size))
+				}
+			} else if format.isText() {
+				fmt.Printf("Would remove %s %s (%s): %s\n", c.app, c.version, formatSize(c.size), c.reason)
+			}
+			doc.Candidates = append(doc.Candidates, pruneCandidateJSON{
+				App:     c.app,
+				Version: c.version,
+				Path:    filepath.Join(baseDir, c.app, c.version),
+				Size:    c.size,
+				Reason:  c.reason,
+				Removed: apply,
+			})
+			doc.Reclaimed += c.size
+		}
+
+		switch {
+		case !format.isText():
+			printOutput(format, doc, doc.Candidates)
+		case len(candidates) == 0:
+			fmt.Println("Nothing to prune")
+		case apply:
+			fmt.Printf("Reclaimed %s\n", formatSize(doc.Reclaimed))
+		default:
+			fmt.Printf("Would reclaim %s; run with --apply to remove\n", formatSize(doc.Reclaimed))
+		}
+
+	case "relink":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printRelinkHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], map[string]bool{"force": false})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) != 0 {
+			fmt.Fprintln(os.Stderr, "Usage: lav relink [--force]")
+			os.Exit(1)
+		}
+
+		changed, err := relinkApps(baseDir, flags["force"] != "")
+		for _, link := range changed {
+			fmt.Printf("Relinked %s\n", link)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(changed) == 0 {
+			fmt.Println("All links are up to date")
+		}
+
+	case "which":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printWhichHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], outputFlags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: lav which <executable> [--json|--format <template>]")
+			os.Exit(1)
+		}
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		links, err := whichBin(baseDir, args[0], cwd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		shadowed := shadowingExecutable(args[0], links)
+		if !format.isText() {
+			doc := whichJSON{jsonHeader: newJSONHeader(), Links: []linkJSON{}, Shadowed: shadowed}
+			for _, info := range links {
+				doc.Links = append(doc.Links, linkJSON{
+					Link: info.link, Target: info.target, App: info.app, Version: info.version,
+					Source: info.source, File: info.file, Shim: info.shim,
+				})
+			}
+			printOutput(format, doc, doc.Links)
+			return
+		}
+		for _, info := range links {
+			printBinLink(info)
+		}
+		if shadowed != "" {
+			fmt.Printf("Warning: %s comes first on PATH, so it runs instead\n", shadowed)
+		}
+
+	case "owner":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printOwnerHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], outputFlags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "Usage: lav owner <path>... [--json|--format <template>]")
+			os.Exit(1)
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		doc := ownerListJSON{jsonHeader: newJSONHeader(), Owners: []ownerJSON{}}
+		failed := false
+		for _, path := range args {
+			owner, err := pathOwner(baseDir, path, cwd)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed = true
+				continue
+			}
+			doc.Owners = append(doc.Owners, ownerJSON{
+				Path: owner.path, App: owner.app, Version: owner.version, File: owner.file, Link: owner.link,
+			})
+		}
+		if !format.isText() {
+			printOutput(format, doc, doc.Owners)
+		} else {
+			for _, owner := range doc.Owners {
+				printOwner(owner)
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+
+	case "alternatives":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printAlternativesHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], withOutputFlags(map[string]bool{
+			"add": true, "priority": true, "remove": true, "auto": false, "force": false,
+		}))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 || len(args) > 2 {
+			fmt.Fprintln(os.Stderr, "Usage: lav alternatives <executable> [app] [--auto] [--add <app> --priority <n>] [--remove <app>]")
+			os.Exit(1)
+		}
+
+		name := args[0]
+		force := flags["force"] != ""
+		switch {
+		case flags["add"] != "":
+			priority := 0
+			if flags["priority"] != "" {
+				if priority, err = strconv.Atoi(flags["priority"]); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: invalid priority: %s\n", flags["priority"])
+					os.Exit(1)
+				}
+			}
+			err = addAlternative(baseDir, name, flags["add"], priority, force)
+		case flags["remove"] != "":
+			err = removeAlternative(baseDir, name, flags["remove"], force)
+		case flags["auto"] != "":
+			err = setAlternative(baseDir, name, "", force)
+		case len(args) == 2:
+			err = setAlternative(baseDir, name, args[1], force)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		providers, active, manual, err := listAlternatives(baseDir, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		mode := "auto"
+		if manual != "" && manual == active {
+			mode = "manual"
+		}
+		if !format.isText() {
+			doc := alternativesJSON{jsonHeader: newJSONHeader(), Name: name, Mode: mode, Providers: []providerJSON{}}
+			for _, p := range providers {
+				doc.Providers = append(doc.Providers, providerJSON{App: p.app, Priority: p.priority, Available: p.available, Active: p.app == active})
+			}
+			printOutput(format, doc, doc.Providers)
+			return
+		}
+		fmt.Printf("%s (%s mode)\n", name, mode)
+		for _, p := range providers {
+			marker := " "
+			if p.app == active {
+				marker = "*"
+			}
+			line := fmt.Sprintf("%s %-20s priority %d", marker, p.app, p.priority)
+			if !p.available {
+				line += " (unavailable)"
+			}
+			fmt.Println(line)
+		}
+
+	case "doctor":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printDoctorHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], withOutputFlags(map[string]bool{"fix": false}))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format, err := parseOutputFormat(flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) != 0 {
+			fmt.Fprintln(os.Stderr, "Usage: lav doctor [--fix] [--json|--format <template>]")
+			os.Exit(1)
+		}
+		_, fix := flags["fix"]
+
+		findings, err := runDoctor(baseDir, fix)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		broken, fixable := false, false
+		for _, f := range findings {
+			if f.Severity == severityError && !f.Fixed {
+				broken = true
+			}
+			if f.Fix != "" && !f.Fixed {
+				fixable = true
+			}
+		}
+
+		if !format.isText() {
+			printOutput(format, doctorJSON{jsonHeader: newJSONHeader(), Findings: findings}, findings)
+		} else {
+			for _, f := range findings {
+				printDoctorFinding(f, fix)
+			}
+			if len(findings) == 0 {
+				fmt.Println("No problems found")
+			} else if fixable && !fix {
+				fmt.Println("Run 'lav doctor --fix' to apply the fixes")
+			}
+		}
+		if broken {
+			os.Exit(1)
+		}
+
+	case "init":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printInitHelp()
+			return
+		}
+
+		args, flags, err := parseArgs(os.Args[2:], map[string]bool{"hook": false})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: lav init <bash|zsh|fish> [--hook]")
+			os.Exit(1)
+		}
+
+		binDirs, err := absBinDirs()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		_, hook := flags["hook"]
+		script, err := initScript(args[0], binDirs, os.Getenv("PATH"), hook)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(script)
+
+	case "__complete":
+		for _, candidate := range completeWords(baseDir, os.Args[2:]) {
+			fmt.Println(candidate)
+		}
+
+	case "__hook":
+		if len(os.Args) != 3 {
+			os.Exit(1)
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lav: %v\n", err)
+			os.Exit(1)
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lav: %v\n", err)
+			os.Exit(1)
+		}
+		lines, warnings, err := hookChanges(baseDir, cwd, os.Environ(), cfg, os.Args[2])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lav: %v\n", err)
+			os.Exit(1)
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "lav: %s\n", warning)
+		}
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+
+	case "trust":
+		if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
+			printTrustHelp()
+			return
+		}
+
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "Usage: lav trust <add|list|remove> <app> [args]")
+			os.Exit(1)
+		}
+
+		switch sub := os.Args[2]; {
+		case sub == "add" && len(os.Args) == 5:
+			app := os.Args[3]
+			key, err := addTrustedKey(baseDir, app, os.Args[4])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Trusted key %s for %s\n", key.keyID(), app)
+
+		case sub == "list":
+			args, flags, err := parseArgs(os.Args[3:], outputFlags)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			format, err := parseOutputFormat(flags)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if len(args) > 1 {
+				fmt.Fprintln(os.Stderr, "Usage: lav trust list [app] [--json|--format <template>]")
+				os.Exit(1)
+			}
+
+			var apps []string
+			if len(args) == 1 {
+				apps = args
+			} else {
+				entries, _ := os.ReadDir(filepath.Join(baseDir, ".trust"))
+				for _, entry := range entries {
+					apps = append(apps, entry.Name())
+				}
+			}
+
+			doc := trustListJSON{jsonHeader: newJSONHeader(), Keys: []trustKeyJSON{}}
+			for _, app := range apps {
+				keys, err := loadTrustedKeys(baseDir, app)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				for _, key := range keys {
+					if format.isText() {
+						fmt.Printf("%s: %s %s\n", app, key.keyID(), key.encode())
+					}
+					doc.Keys = append(doc.Keys, trustKeyJSON{App: app, KeyID: key.keyID(), Key: key.encode()})
+				}
+			}
+			if !format.isText() {
+				printOutput(format, doc, doc.Keys)
+			}
+
+		case sub == "remove" && len(os.Args) == 5:
+			app := os.Args[3]
+			if err := removeTrustedKey(baseDir, app, os.Args[4]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Removed key %s for %s\n", strings.ToUpper(os.Args[4]), app)
+
+		default:
+			fmt.Fprintln(os.Stderr, "Usage: lav trust <add|list|remove> <app> [args]")
 			os.Exit(1)
 		}
 
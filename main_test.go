@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -13,18 +14,20 @@ func TestListVersions(t *testing.T) {
 	os.MkdirAll(filepath.Join(appDir, "1.0.0"), 0755)
 	os.MkdirAll(filepath.Join(appDir, "2.0.0"), 0755)
 	os.MkdirAll(filepath.Join(appDir, "1.1.0"), 0755)
+	os.MkdirAll(filepath.Join(appDir, "1.10.0"), 0755)
+	os.MkdirAll(filepath.Join(appDir, "2.0.0-beta2"), 0755)
 
 	versions, err := listVersions(tmpDir, "testapp")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if len(versions) != 3 {
-		t.Errorf("expected 3 versions, got %d", len(versions))
+	if len(versions) != 5 {
+		t.Fatalf("expected 5 versions, got %d", len(versions))
 	}
 
-	// Should be sorted
-	expected := []string{"1.0.0", "1.1.0", "2.0.0"}
+	// Should be sorted by version precedence
+	expected := []string{"1.0.0", "1.1.0", "1.10.0", "2.0.0-beta2", "2.0.0"}
 	for i, v := range versions {
 		if v != expected[i] {
 			t.Errorf("expected versions[%d]=%s, got %s", i, expected[i], v)
@@ -82,13 +85,14 @@ func TestGetCurrentVersion_NoSymlink(t *testing.T) {
 }
 
 func TestSwitchVersion(t *testing.T) {
+	setupTestEnv(t)
 	tmpDir := t.TempDir()
 	appDir := filepath.Join(tmpDir, "testapp")
 	os.MkdirAll(filepath.Join(appDir, "1.0.0"), 0755)
 	os.MkdirAll(filepath.Join(appDir, "2.0.0"), 0755)
 
 	// Switch to 1.0.0
-	err := switchVersion(tmpDir, "testapp", "1.0.0")
+	err := switchVersion(tmpDir, "testapp", "1.0.0", false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -99,7 +103,7 @@ func TestSwitchVersion(t *testing.T) {
 	}
 
 	// Switch to 2.0.0
-	err = switchVersion(tmpDir, "testapp", "2.0.0")
+	err = switchVersion(tmpDir, "testapp", "2.0.0", false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -111,11 +115,12 @@ func TestSwitchVersion(t *testing.T) {
 }
 
 func TestSwitchVersion_NotExists(t *testing.T) {
+	setupTestEnv(t)
 	tmpDir := t.TempDir()
 	appDir := filepath.Join(tmpDir, "testapp")
 	os.MkdirAll(appDir, 0755)
 
-	err := switchVersion(tmpDir, "testapp", "1.0.0")
+	err := switchVersion(tmpDir, "testapp", "1.0.0", false)
 	if err == nil {
 		t.Error("expected error for non-existent version")
 	}
@@ -135,3 +140,235 @@ func TestListApps(t *testing.T) {
 		t.Errorf("expected 2 apps, got %d", len(apps))
 	}
 }
+
+func TestParseArgs(t *testing.T) {
+	args, flags, err := parseArgs(
+		[]string{"./app.tar.gz", "--sha256", "abc", "app", "--checksums=SUMS", "1.0.0"},
+		map[string]bool{"sha256": true, "checksums": true},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(args, " ") != "./app.tar.gz app 1.0.0" {
+		t.Errorf("unexpected positional args: %v", args)
+	}
+	if flags["sha256"] != "abc" || flags["checksums"] != "SUMS" {
+		t.Errorf("unexpected flags: %v", flags)
+	}
+
+	if _, _, err := parseArgs([]string{"--bogus"}, nil); err == nil {
+		t.Error("expected error for unknown flag")
+	}
+	if _, _, err := parseArgs([]string{"--sha256"}, map[string]bool{"sha256": true}); err == nil {
+		t.Error("expected error for missing flag value")
+	}
+}
+
+func TestReplaceSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	link := filepath.Join(tmpDir, "current")
+
+	if err := replaceSymlink("1.0.0", link); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := replaceSymlink("2.0.0", link); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	target, _ := os.Readlink(link)
+	if target != "2.0.0" {
+		t.Errorf("expected 2.0.0, got %s", target)
+	}
+
+	// No temporary links are left behind
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the link, got %d entries", len(entries))
+	}
+}
+
+func TestReplaceSymlink_NotSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "go")
+	os.WriteFile(file, []byte("real binary"), 0755)
+
+	if err := replaceSymlink("target", file); err == nil {
+		t.Error("expected error when replacing a regular file")
+	}
+	if data, _ := os.ReadFile(file); string(data) != "real binary" {
+		t.Error("regular file should not be modified")
+	}
+}
+
+func TestSwitchVersion_Atomic(t *testing.T) {
+	setupTestEnv(t)
+	tmpDir := t.TempDir()
+	appDir := filepath.Join(tmpDir, "testapp")
+	os.MkdirAll(filepath.Join(appDir, "1.0.0"), 0755)
+	os.MkdirAll(filepath.Join(appDir, "2.0.0"), 0755)
+	switchVersion(tmpDir, "testapp", "1.0.0", false)
+
+	// Readers never observe a missing current link while versions switch
+	done := make(chan struct{})
+	failed := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if _, err := os.Stat(filepath.Join(appDir, "current")); err != nil {
+				failed <- err
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		version := []string{"1.0.0", "2.0.0"}[i%2]
+		if err := switchVersion(tmpDir, "testapp", version, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	close(done)
+
+	select {
+	case err := <-failed:
+		t.Errorf("current link was missing during switch: %v", err)
+	default:
+	}
+}
+
+func TestActivateVersion_RestoresCurrent(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	installTestVersion(t, baseDir, "app", "1.0.0", "app")
+
+	newBin := filepath.Join(baseDir, "app", "2.0.0", "bin")
+	os.MkdirAll(newBin, 0755)
+	os.WriteFile(filepath.Join(newBin, "app"), []byte("app 2.0.0"), 0755)
+
+	// A bin directory under a file passes the conflict check but cannot be
+	// synced
+	blocker := filepath.Join(home, "blocker")
+	os.WriteFile(blocker, nil, 0644)
+	t.Setenv("LAV_BIN_DIR", filepath.Join(blocker, "bin"))
+
+	if err := activateVersion(baseDir, "app", "2.0.0", false); err == nil {
+		t.Fatal("expected an error when the bin links cannot be synced")
+	}
+	if current, _ := getCurrentVersion(baseDir, "app"); current != "1.0.0" {
+		t.Errorf("expected current to stay 1.0.0, got %q", current)
+	}
+}
+
+func TestGetBinDirs(t *testing.T) {
+	home := setupTestEnv(t)
+	configPath := filepath.Join(home, "config.toml")
+	t.Setenv("LAV_BIN_DIR", "")
+
+	dirs, err := getBinDirs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(home, ".local", "bin"); len(dirs) != 1 || dirs[0] != want {
+		t.Errorf("expected [%s], got %v", want, dirs)
+	}
+
+	os.WriteFile(configPath, []byte(`bin_dir = ["~/bin", "/opt/team/bin"]`+"\n"), 0644)
+	dirs, _ = getBinDirs()
+	if strings.Join(dirs, ",") != filepath.Join(home, "bin")+",/opt/team/bin" {
+		t.Errorf("expected config bin_dir, got %v", dirs)
+	}
+
+	t.Setenv("LAV_BIN_DIR", "/a/bin"+string(os.PathListSeparator)+"/b/bin")
+	dirs, _ = getBinDirs()
+	if strings.Join(dirs, ",") != "/a/bin,/b/bin" {
+		t.Errorf("expected LAV_BIN_DIR, got %v", dirs)
+	}
+
+	binDirFlag = []string{"/flag/bin"}
+	defer func() { binDirFlag = nil }()
+	dirs, _ = getBinDirs()
+	if strings.Join(dirs, ",") != "/flag/bin" {
+		t.Errorf("expected --bin-dir, got %v", dirs)
+	}
+}
+
+func TestExtractBinDirFlag(t *testing.T) {
+	defer func() { binDirFlag = nil }()
+
+	args, err := extractBinDirFlag([]string{"lav", "--bin-dir", "/a", "install", "--bin-dir=/b", "x", "--", "--bin-dir"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(args, " ") != "lav install x -- --bin-dir" {
+		t.Errorf("unexpected args: %v", args)
+	}
+	if strings.Join(binDirFlag, ",") != "/a,/b" {
+		t.Errorf("unexpected bin dirs: %v", binDirFlag)
+	}
+
+	if _, err := extractBinDirFlag([]string{"lav", "use", "--bin-dir"}); err == nil {
+		t.Error("expected error for missing flag value")
+	}
+}
+
+func TestCreateBinSymlinks_MultipleBinDirs(t *testing.T) {
+	setupTestEnv(t)
+	team := filepath.Join(t.TempDir(), "team", "bin")
+	personal := filepath.Join(t.TempDir(), "bin")
+	t.Setenv("LAV_BIN_DIR", team+string(os.PathListSeparator)+personal)
+
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.23.0", "go")
+
+	for _, dir := range []string{team, personal} {
+		if _, err := os.Stat(filepath.Join(dir, "go")); err != nil {
+			t.Errorf("expected link in %s: %v", dir, err)
+		}
+	}
+
+	removed, err := removeApp(baseDir, "go", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("expected links removed from both directories, got %v", removed)
+	}
+}
+
+// installTestVersion installs version of app with the given executables in
+// its bin/ directory, linking them into the test's bin directory.
+// setupTestEnv keeps a test away from the user's setup: HOME, the XDG
+// directories, the config file (an empty one) and the bin directory all
+// point into a temporary home, which it returns. Tests that install or
+// activate versions call it, so that links land in <home>/.local/bin.
+func setupTestEnv(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	configPath := filepath.Join(home, "config.toml")
+	if err := os.WriteFile(configPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("LAV_CONFIG", configPath)
+	t.Setenv("LAV_BIN_DIR", filepath.Join(home, ".local", "bin"))
+	t.Setenv("LAV_ROOT", "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	return home
+}
+
+func installTestVersion(t *testing.T, baseDir, app, version string, bins ...string) {
+	t.Helper()
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	for _, bin := range bins {
+		os.WriteFile(filepath.Join(src, "bin", bin), []byte(app+" "+version), 0755)
+	}
+	if err := installDirectory(baseDir, src, app, version, false); err != nil {
+		t.Fatalf("failed to install %s %s: %v", app, version, err)
+	}
+}
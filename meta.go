@@ -0,0 +1,195 @@
+package main
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// metaFileName is the file in each version directory that records where
+// the version came from.
+const metaFileName = ".lav-meta.json"
+
+// versionMeta is the install metadata of a version.
+type versionMeta struct {
+	Source      string    `json:"source"`           // absolute path or URL installed from
+	SHA256      string    `json:"sha256,omitempty"` // of the file or archive; "" for folders
+	InstalledAt time.Time `json:"installed_at"`
+	LavVersion  string    `json:"lav_version"`
+	Arch        string    `json:"arch,omitempty"` // e.g. linux/amd64, detected from bin/
+	Files       int       `json:"files"`
+	Size        int64     `json:"size"`
+}
+
+func metaPath(baseDir, app, version string) string {
+	return filepath.Join(baseDir, app, version, metaFileName)
+}
+
+// readVersionMeta reads the install metadata of version. Versions installed
+// before lav recorded metadata have none; nil is returned for them.
+func readVersionMeta(baseDir, app, version string) (*versionMeta, error) {
+	data, err := os.ReadFile(metaPath(baseDir, app, version))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var meta versionMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("invalid install metadata %s: %w", metaPath(baseDir, app, version), err)
+	}
+	return &meta, nil
+}
+
+// writeVersionMeta replaces the install metadata of version.
+func writeVersionMeta(baseDir, app, version string, meta versionMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := metaPath(baseDir, app, version)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// newVersionMeta returns the install metadata of the version directory dir,
+// just installed from source, whose artifact had checksum sum.
+func newVersionMeta(dir, source, sum string) (versionMeta, error) {
+	files, size, err := versionStats(dir)
+	if err != nil {
+		return versionMeta{}, err
+	}
+
+	return versionMeta{
+		Source:      source,
+		SHA256:      sum,
+		InstalledAt: time.Now().UTC().Truncate(time.Second),
+		LavVersion:  version,
+		Arch:        detectArch(dir),
+		Files:       files,
+		Size:        size,
+	}, nil
+}
+
+// versionStats returns the number and total size of the regular files in
+// the version directory dir, not counting the files lav keeps there.
+func versionStats(dir string) (int, int64, error) {
+	var files int
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || filepath.Dir(path) == dir && isLavFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files++
+		size += info.Size()
+		return nil
+	})
+	return files, size, err
+}
+
+// detectArch returns the platform of the first native executable in the
+// bin directory of dir, such as "linux/amd64", or "" if there is none.
+func detectArch(dir string) string {
+	entries, err := os.ReadDir(filepath.Join(dir, "bin"))
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if arch := executableArch(filepath.Join(dir, "bin", entry.Name())); arch != "" {
+			return arch
+		}
+	}
+	return ""
+}
+
+// executableArch returns the platform of the ELF, Mach-O or PE executable
+// at path, or "" if it is none of those.
+func executableArch(path string) string {
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		goos := "linux"
+		if f.OSABI == elf.ELFOSABI_FREEBSD {
+			goos = "freebsd"
+		}
+		switch f.Machine {
+		case elf.EM_X86_64:
+			return goos + "/amd64"
+		case elf.EM_AARCH64:
+			return goos + "/arm64"
+		case elf.EM_386:
+			return goos + "/386"
+		case elf.EM_ARM:
+			return goos + "/arm"
+		case elf.EM_RISCV:
+			return goos + "/riscv64"
+		}
+		return goos + "/" + f.Machine.String()
+	}
+
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		switch f.Cpu {
+		case macho.CpuAmd64:
+			return "darwin/amd64"
+		case macho.CpuArm64:
+			return "darwin/arm64"
+		}
+		return "darwin/" + f.Cpu.String()
+	}
+	if f, err := macho.OpenFat(path); err == nil {
+		f.Close()
+		return "darwin/universal"
+	}
+
+	if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		switch f.Machine {
+		case pe.IMAGE_FILE_MACHINE_AMD64:
+			return "windows/amd64"
+		case pe.IMAGE_FILE_MACHINE_ARM64:
+			return "windows/arm64"
+		case pe.IMAGE_FILE_MACHINE_I386:
+			return "windows/386"
+		}
+		return fmt.Sprintf("windows/0x%x", f.Machine)
+	}
+	return ""
+}
+
+// formatMetaSummary describes where a version came from in one line.
+func formatMetaSummary(meta *versionMeta) string {
+	if meta == nil {
+		return "no install metadata"
+	}
+	s := fmt.Sprintf("installed %s from %s", meta.InstalledAt.Local().Format("2006-01-02"), meta.Source)
+	if meta.Arch != "" {
+		s += " (" + meta.Arch + ")"
+	}
+	return s
+}
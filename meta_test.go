@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestInstallPath_RecordsMeta(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	binary := filepath.Join(t.TempDir(), "tool")
+	os.WriteFile(binary, []byte("tool contents"), 0755)
+
+	if err := installPath(baseDir, binary, "tool", "1.0.0", installOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	meta, err := readVersionMeta(baseDir, "tool", "1.0.0")
+	if err != nil || meta == nil {
+		t.Fatalf("expected metadata, got %v %v", meta, err)
+	}
+	if meta.Source != binary || meta.SHA256 != sha256Hex([]byte("tool contents")) {
+		t.Errorf("unexpected source or checksum: %+v", meta)
+	}
+	if meta.Files != 1 || meta.Size != int64(len("tool contents")) {
+		t.Errorf("metadata should count the installed files only: %+v", meta)
+	}
+	if meta.LavVersion != version || meta.InstalledAt.IsZero() {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+}
+
+func TestInstallPath_DirectoryMeta(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	os.WriteFile(filepath.Join(src, "bin", "tool"), []byte("tool"), 0755)
+	os.WriteFile(filepath.Join(src, "README"), []byte("readme"), 0644)
+
+	if err := installPath(baseDir, src, "tool", "1.0.0", installOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	meta, err := readVersionMeta(baseDir, "tool", "1.0.0")
+	if err != nil || meta == nil {
+		t.Fatalf("expected metadata, got %v %v", meta, err)
+	}
+	if meta.SHA256 != "" || meta.Files != 2 || meta.Size != 10 {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+}
+
+func TestReadVersionMeta_Missing(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+
+	meta, err := readVersionMeta(baseDir, "go", "1.22.0")
+	if err != nil || meta != nil {
+		t.Errorf("expected no metadata, got %v %v", meta, err)
+	}
+	if formatMetaSummary(meta) != "no install metadata" {
+		t.Errorf("unexpected summary: %s", formatMetaSummary(meta))
+	}
+}
+
+func TestExecutableArch(t *testing.T) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arch, want := executableArch(self), runtime.GOOS+"/"+runtime.GOARCH; arch != want {
+		t.Errorf("expected %s, got %q", want, arch)
+	}
+
+	script := filepath.Join(t.TempDir(), "script")
+	os.WriteFile(script, []byte("#!/bin/sh\n"), 0755)
+	if arch := executableArch(script); arch != "" {
+		t.Errorf("expected no platform for a script, got %q", arch)
+	}
+}
+
+func TestDetectArch(t *testing.T) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "bin"), 0755)
+	os.WriteFile(filepath.Join(dir, "bin", "a-script"), []byte("#!/bin/sh\n"), 0755)
+	if err := copyFile(self, filepath.Join(dir, "bin", "tool")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Scripts are skipped
+	if arch, want := detectArch(dir), runtime.GOOS+"/"+runtime.GOARCH; arch != want {
+		t.Errorf("expected %s, got %q", want, arch)
+	}
+}
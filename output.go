@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// jsonSchemaVersion is the version of the documents printed with --json. It
+// is increased when a field is removed or changes meaning; fields may be
+// added without increasing it.
+const jsonSchemaVersion = 1
+
+// outputFlags are the flags of commands that support machine-readable
+// output, in the form taken by parseArgs.
+var outputFlags = map[string]bool{"json": false, "format": true}
+
+// withOutputFlags returns flags with outputFlags added.
+func withOutputFlags(flags map[string]bool) map[string]bool {
+	merged := make(map[string]bool)
+	for name, takesValue := range flags {
+		merged[name] = takesValue
+	}
+	for name, takesValue := range outputFlags {
+		merged[name] = takesValue
+	}
+	return merged
+}
+
+// outputFormat selects how a command prints its result: as text (the
+// zero value), as a JSON document, or through a Go template executed for
+// each record.
+type outputFormat struct {
+	json     bool
+	template *template.Template
+}
+
+// parseOutputFormat returns the output format selected by the --json and
+// --format flags.
+func parseOutputFormat(flags map[string]string) (outputFormat, error) {
+	_, asJSON := flags["json"]
+	text, hasFormat := flags["format"]
+	if asJSON && hasFormat {
+		return outputFormat{}, fmt.Errorf("--json and --format cannot be used together")
+	}
+	if !hasFormat {
+		return outputFormat{json: asJSON}, nil
+	}
+
+	tmpl, err := template.New("format").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return outputFormat{}, fmt.Errorf("invalid --format template: %w", err)
+	}
+	return outputFormat{template: tmpl}, nil
+}
+
+// isText reports whether the command should print its usual text output.
+func (f outputFormat) isText() bool {
+	return !f.json && f.template == nil
+}
+
+// print writes doc as indented JSON, or executes the template for each
+// element of records, the slice of records in doc. Templates see the
+// records with the field names of the JSON document, e.g. {{.version}}.
+func (f outputFormat) print(w io.Writer, doc, records any) error {
+	if f.json {
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	// Decode numbers as json.Number so that sizes are not printed in
+	// floating-point notation
+	var values []any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if err := f.template.Execute(w, value); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// printOutput prints doc with format, exiting on failure. For a single
+// record, records may be the record itself.
+func printOutput(format outputFormat, doc, records any) {
+	if v := reflect.ValueOf(records); v.Kind() != reflect.Slice {
+		records = []any{records}
+	}
+	if err := format.print(os.Stdout, doc, records); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// jsonHeader starts every JSON document.
+type jsonHeader struct {
+	SchemaVersion int `json:"schema_version"`
+}
+
+func newJSONHeader() jsonHeader {
+	return jsonHeader{SchemaVersion: jsonSchemaVersion}
+}
+
+// appJSON describes an installed app in lav list.
+type appJSON struct {
+	App      string        `json:"app"`
+	Current  string        `json:"current,omitempty"`
+	Path     string        `json:"path"`
+	Versions []versionJSON `json:"versions"`
+}
+
+// versionJSON describes an installed version.
+type versionJSON struct {
+	App         string    `json:"app"`
+	Version     string    `json:"version"`
+	Current     bool      `json:"current"`
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	InstalledAt time.Time `json:"installed_at"`
+	// Meta is the install metadata, absent for versions installed before
+	// lav recorded it
+	Meta *versionMeta `json:"meta,omitempty"`
+}
+
+// listJSON is the document printed by lav list --json.
+type listJSON struct {
+	jsonHeader
+	Apps []appJSON `json:"apps"`
+}
+
+// infoJSON is the document printed by lav info --json.
+type infoJSON struct {
+	jsonHeader
+	versionJSON
+}
+
+// appVersionsJSON is the document printed by lav list <app> --json.
+type appVersionsJSON struct {
+	jsonHeader
+	appJSON
+}
+
+// currentJSON describes the version of an app in effect, as shown by lav
+// current. Source is "current", an environment variable, "lav shell" or the
+// path of a project version file.
+type currentJSON struct {
+	App     string `json:"app"`
+	Version string `json:"version"`
+	Source  string `json:"source"`
+	Path    string `json:"path"`
+}
+
+// currentListJSON is the document printed by lav current --json.
+type currentListJSON struct {
+	jsonHeader
+	Current []currentJSON `json:"current"`
+}
+
+// projectVersionJSON describes a version set by a project version file.
+type projectVersionJSON struct {
+	App     string `json:"app"`
+	Version string `json:"version"`
+	File    string `json:"file"`
+}
+
+// localJSON is the document printed by lav local --json.
+type localJSON struct {
+	jsonHeader
+	Versions []projectVersionJSON `json:"versions"`
+}
+
+// linkJSON describes a bin link found by lav which. Version is the version
+// the link runs, Source where that version comes from, and File the file it
+// runs, absent if the link is dangling.
+type linkJSON struct {
+	Link    string `json:"link"`
+	Target  string `json:"target"`
+	App     string `json:"app,omitempty"`
+	Version string `json:"version,omitempty"`
+	Source  string `json:"source,omitempty"`
+	File    string `json:"file,omitempty"`
+	Shim    bool   `json:"shim,omitempty"`
+}
+
+// whichJSON is the document printed by lav which --json. Shadowed is set
+// when another executable of the same name comes before the links on PATH.
+type whichJSON struct {
+	jsonHeader
+	Links    []linkJSON `json:"links"`
+	Shadowed string     `json:"shadowed,omitempty"`
+}
+
+// ownerJSON describes the owner of a path, as shown by lav owner.
+type ownerJSON struct {
+	Path    string `json:"path"`
+	App     string `json:"app"`
+	Version string `json:"version,omitempty"`
+	File    string `json:"file,omitempty"`
+	Link    string `json:"link,omitempty"`
+}
+
+// ownerListJSON is the document printed by lav owner --json.
+type ownerListJSON struct {
+	jsonHeader
+	Owners []ownerJSON `json:"owners"`
+}
+
+// providerJSON describes a provider listed by lav alternatives.
+type providerJSON struct {
+	App       string `json:"app"`
+	Priority  int    `json:"priority"`
+	Available bool   `json:"available"`
+	Active    bool   `json:"active"`
+}
+
+// alternativesJSON is the document printed by lav alternatives --json.
+type alternativesJSON struct {
+	jsonHeader
+	Name      string         `json:"name"`
+	Mode      string         `json:"mode"`
+	Providers []providerJSON `json:"providers"`
+}
+
+// findingJSON describes a problem found by lav doctor. Severity is
+// "error", "warning" or "note"; Fix describes what --fix does about it, if
+// anything.
+type findingJSON struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix,omitempty"`
+	Fixed    bool   `json:"fixed,omitempty"`
+	FixError string `json:"fix_error,omitempty"`
+}
+
+// doctorJSON is the document printed by lav doctor --json.
+type doctorJSON struct {
+	jsonHeader
+	Findings []findingJSON `json:"findings"`
+}
+
+// pruneCandidateJSON describes a version selected by lav prune.
+type pruneCandidateJSON struct {
+	App     string `json:"app"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Reason  string `json:"reason"`
+	Removed bool   `json:"removed"`
+}
+
+// pruneJSON is the document printed by lav prune --json. Reclaimed is the
+// total size of the candidates, removed or not.
+type pruneJSON struct {
+	jsonHeader
+	Applied    bool                 `json:"applied"`
+	Candidates []pruneCandidateJSON `json:"candidates"`
+	Reclaimed  int64                `json:"reclaimed"`
+}
+
+// verifiedJSON describes the outcome of verifying a version. Status is
+// "ok", "failed", "repaired" or "unverified" for versions without a
+// manifest; Error explains why a failed version could not be repaired.
+type verifiedJSON struct {
+	App     string        `json:"app"`
+	Version string        `json:"version"`
+	Status  string        `json:"status"`
+	Issues  []verifyIssue `json:"issues"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// verifyJSON is the document printed by lav verify --json.
+type verifyJSON struct {
+	jsonHeader
+	Versions []verifiedJSON `json:"versions"`
+}
+
+// trustKeyJSON describes a trusted signing key.
+type trustKeyJSON struct {
+	App   string `json:"app"`
+	KeyID string `json:"key_id"`
+	Key   string `json:"key"`
+}
+
+// trustListJSON is the document printed by lav trust list --json.
+type trustListJSON struct {
+	jsonHeader
+	Keys []trustKeyJSON `json:"keys"`
+}
+
+// changeJSON is the document printed by install, use and remove with
+// --json, describing what they changed.
+type changeJSON struct {
+	jsonHeader
+	Action   string `json:"action"` // "install", "use" or "remove"
+	App      string `json:"app"`
+	Version  string `json:"version,omitempty"`  // "" when a whole app was removed
+	Previous string `json:"previous,omitempty"` // current version before the change
+	Current  string `json:"current,omitempty"`  // current version after the change
+	Path     string `json:"path,omitempty"`
+	// Links lists the app's bin links after the change
+	Links []string `json:"links,omitempty"`
+	// RemovedLinks lists the links remove deleted or handed to another
+	// provider of the executable
+	RemovedLinks []string `json:"removed_links,omitempty"`
+}
+
+// describeVersion returns the description of version of app.
+func describeVersion(baseDir, app, version, current string) (versionJSON, error) {
+	dir := filepath.Join(baseDir, app, version)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return versionJSON{}, err
+	}
+	_, size, err := versionStats(dir)
+	if err != nil {
+		return versionJSON{}, err
+	}
+	meta, err := readVersionMeta(baseDir, app, version)
+	if err != nil {
+		return versionJSON{}, err
+	}
+
+	installedAt := info.ModTime().UTC()
+	if meta != nil {
+		installedAt = meta.InstalledAt
+	}
+	return versionJSON{
+		App:         app,
+		Version:     version,
+		Current:     version == current,
+		Path:        dir,
+		Size:        size,
+		InstalledAt: installedAt,
+		Meta:        meta,
+	}, nil
+}
+
+// describeApp returns the description of app and its versions.
+func describeApp(baseDir, app string) (appJSON, error) {
+	versions, err := listVersions(baseDir, app)
+	if err != nil {
+		return appJSON{}, err
+	}
+	current, _ := getCurrentVersion(baseDir, app)
+
+	desc := appJSON{App: app, Current: current, Path: filepath.Join(baseDir, app), Versions: []versionJSON{}}
+	for _, version := range versions {
+		v, err := describeVersion(baseDir, app, version, current)
+		if err != nil {
+			return appJSON{}, err
+		}
+		desc.Versions = append(desc.Versions, v)
+	}
+	return desc, nil
+}
+
+// appLinks returns the bin links the registry records for app, sorted.
+func appLinks(baseDir, app string) []string {
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		return nil
+	}
+	var links []string
+	for link, owner := range reg.Links {
+		if owner == app {
+			links = append(links, link)
+		}
+	}
+	sort.Strings(links)
+	return links
+}
+
+// describeChange returns the result of action on version of app, given the
+// current version before it.
+func describeChange(baseDir, action, app, version, previous string) changeJSON {
+	current, _ := getCurrentVersion(baseDir, app)
+	change := changeJSON{
+		jsonHeader: newJSONHeader(),
+		Action:     action,
+		App:        app,
+		Version:    version,
+		Previous:   previous,
+		Current:    current,
+		Links:      appLinks(baseDir, app),
+	}
+	if action != "remove" {
+		change.Path = filepath.Join(baseDir, app, version)
+	}
+	return change
+}
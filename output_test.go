@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	format, err := parseOutputFormat(map[string]string{})
+	if err != nil || !format.isText() {
+		t.Errorf("expected text output, got %+v %v", format, err)
+	}
+	format, err = parseOutputFormat(map[string]string{"json": "true"})
+	if err != nil || !format.json {
+		t.Errorf("expected JSON output, got %+v %v", format, err)
+	}
+	if _, err := parseOutputFormat(map[string]string{"json": "true", "format": "{{.app}}"}); err == nil {
+		t.Error("expected error for --json with --format")
+	}
+	if _, err := parseOutputFormat(map[string]string{"format": "{{.app"}); err == nil {
+		t.Error("expected error for an invalid template")
+	}
+}
+
+func TestOutputFormatPrint(t *testing.T) {
+	records := []versionJSON{
+		{App: "go", Version: "1.22.0", Size: 123456789},
+		{App: "go", Version: "1.23.4", Current: true, Size: 42},
+	}
+	doc := appVersionsJSON{jsonHeader: newJSONHeader(), appJSON: appJSON{App: "go", Current: "1.23.4", Versions: records}}
+
+	var buf bytes.Buffer
+	format, _ := parseOutputFormat(map[string]string{"format": "{{.version}} {{.size}} {{.current}}"})
+	if err := format.print(&buf, doc, records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "1.22.0 123456789 false\n1.23.4 42 true\n"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	format, _ = parseOutputFormat(map[string]string{"json": "true"})
+	if err := format.print(&buf, doc, records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if decoded["schema_version"] != float64(jsonSchemaVersion) || decoded["app"] != "go" || decoded["current"] != "1.23.4" {
+		t.Errorf("unexpected document: %v", decoded)
+	}
+	if versions, ok := decoded["versions"].([]any); !ok || len(versions) != 2 {
+		t.Errorf("unexpected versions: %v", decoded["versions"])
+	}
+}
+
+func TestDescribeApp(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.4", "go")
+
+	desc, err := describeApp(baseDir, "go")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if desc.Current != "1.23.4" || len(desc.Versions) != 2 {
+		t.Fatalf("unexpected description: %+v", desc)
+	}
+	v := desc.Versions[1]
+	if v.Version != "1.23.4" || !v.Current || v.Path != filepath.Join(baseDir, "go", "1.23.4") {
+		t.Errorf("unexpected version: %+v", v)
+	}
+	if v.Size != int64(len("go 1.23.4")) || v.InstalledAt.IsZero() {
+		t.Errorf("expected size and install time, got %+v", v)
+	}
+	if desc.Versions[0].Current {
+		t.Errorf("1.22.0 should not be current")
+	}
+}
+
+func TestDescribeChange(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go", "gofmt")
+	installTestVersion(t, baseDir, "go", "1.23.4", "go", "gofmt")
+
+	if err := switchVersion(baseDir, "go", "1.22.0", false); err != nil {
+		t.Fatalf("failed to switch: %v", err)
+	}
+	change := describeChange(baseDir, "use", "go", "1.22.0", "1.23.4")
+	if change.Previous != "1.23.4" || change.Current != "1.22.0" || change.Path != filepath.Join(baseDir, "go", "1.22.0") {
+		t.Errorf("unexpected change: %+v", change)
+	}
+	binDir := filepath.Join(home, ".local", "bin")
+	if want := []string{filepath.Join(binDir, "go"), filepath.Join(binDir, "gofmt")}; !slices.Equal(change.Links, want) {
+		t.Errorf("expected links %v, got %v", want, change.Links)
+	}
+
+	removed, err := removeApp(baseDir, "go", true)
+	if err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+	change = describeChange(baseDir, "remove", "go", "", "1.22.0")
+	change.RemovedLinks = removed
+	data, _ := json.Marshal(change)
+	if !strings.Contains(string(data), `"removed_links"`) || strings.Contains(string(data), `"path"`) || strings.Contains(string(data), `"current"`) {
+		t.Errorf("unexpected remove result: %s", data)
+	}
+	if _, err := os.Lstat(filepath.Join(binDir, "go")); !os.IsNotExist(err) {
+		t.Errorf("expected link to be removed")
+	}
+}
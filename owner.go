@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// resolveBinLink follows link, a link in a bin directory, to the file it
+// runs in dir: through the app's current symlink, or for shims, to the
+// version lav would pick there.
+func resolveBinLink(baseDir string, reg *linkRegistry, link, dir string) binLinkInfo {
+	info := binLinkInfo{link: link, app: reg.owner(baseDir, link)}
+	info.target, _ = os.Readlink(link)
+
+	if info.app != "" && isShimLink(link) {
+		info.shim = true
+		version, source, err := resolveActiveVersion(baseDir, info.app, dir)
+		if err != nil {
+			return info
+		}
+		info.version, info.source = version, source
+		file := filepath.Join(baseDir, info.app, version, "bin", filepath.Base(link))
+		if _, err := os.Stat(file); err == nil {
+			info.file = file
+		}
+		return info
+	}
+
+	if file, err := filepath.EvalSymlinks(link); err == nil {
+		info.file = file
+	}
+	if info.app == "" {
+		return info
+	}
+	info.source = "current"
+	if info.file == "" {
+		// Dangling; report the version the link was meant for
+		info.version, _ = getCurrentVersion(baseDir, info.app)
+	} else if owner, err := fileOwner(baseDir, info.file); err == nil && owner.app == info.app {
+		info.version = owner.version
+	}
+	return info
+}
+
+// shadowingExecutable returns the executable named name that comes first on
+// PATH if it is none of the links, or "" if one of the links is found first
+// or name is not on PATH at all.
+func shadowingExecutable(name string, links []binLinkInfo) string {
+	found, err := exec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	foundInfo, err := os.Stat(found)
+	if err != nil {
+		return ""
+	}
+	for _, info := range links {
+		if linkInfo, err := os.Stat(info.link); err == nil && os.SameFile(foundInfo, linkInfo) {
+			return ""
+		}
+	}
+	if abs, err := filepath.Abs(found); err == nil {
+		return abs
+	}
+	return found
+}
+
+// ownerInfo describes the app and version a path belongs to, as shown by
+// lav owner.
+type ownerInfo struct {
+	path    string
+	app     string
+	version string // "" for the app directory itself
+	file    string // slash-separated path within the version directory
+	link    string // the bin link path was resolved from, if any
+}
+
+// fileOwner maps path, a file under the lav root or a symlink into it, to
+// the app and version that own it. Paths through an app's current symlink
+// are attributed to the version it points at.
+func fileOwner(baseDir, path string) (ownerInfo, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ownerInfo{}, err
+	}
+	if _, err := os.Lstat(abs); err != nil {
+		return ownerInfo{}, err
+	}
+
+	// The path itself may lie in the root, or resolve into it
+	for _, candidate := range []string{abs, realPath(abs)} {
+		for _, root := range []string{baseDir, realPath(baseDir)} {
+			absRoot, err := filepath.Abs(root)
+			if err != nil || !isWithin(absRoot, candidate) {
+				continue
+			}
+			rel, err := filepath.Rel(absRoot, candidate)
+			if err != nil || rel == "." {
+				continue
+			}
+			return ownerFromRel(baseDir, abs, filepath.ToSlash(rel))
+		}
+	}
+	return ownerInfo{}, fmt.Errorf("%s is not managed by lav", abs)
+}
+
+// ownerFromRel returns the owner of path, whose location relative to the
+// lav root is rel.
+func ownerFromRel(baseDir, path, rel string) (ownerInfo, error) {
+	parts := strings.SplitN(rel, "/", 3)
+	if strings.HasPrefix(parts[0], ".") {
+		return ownerInfo{}, fmt.Errorf("%s belongs to lav's %s directory, not to an app", path, parts[0])
+	}
+
+	owner := ownerInfo{path: path, app: parts[0]}
+	if len(parts) > 1 {
+		owner.version = parts[1]
+		if owner.version == "current" {
+			current, err := getCurrentVersion(baseDir, owner.app)
+			if err != nil || current == "" {
+				return ownerInfo{}, fmt.Errorf("%s is under the current version of %s, but none is selected", path, owner.app)
+			}
+			owner.version = current
+		}
+		if strings.HasPrefix(owner.version, ".") {
+			return ownerInfo{}, fmt.Errorf("%s belongs to an unfinished operation on %s, not to a version", path, owner.app)
+		}
+	}
+	if len(parts) > 2 {
+		owner.file = parts[2]
+	}
+	return owner, nil
+}
+
+// pathOwner returns the owner of path: a file under the lav root, a symlink
+// into it, or a link in a bin directory, which is resolved as lav which
+// does in dir.
+func pathOwner(baseDir, path, dir string) (ownerInfo, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ownerInfo{}, err
+	}
+
+	binDirs, err := absBinDirs()
+	if err != nil {
+		return ownerInfo{}, err
+	}
+	for _, binDir := range binDirs {
+		if filepath.Dir(abs) != binDir && filepath.Dir(abs) != realPath(binDir) {
+			continue
+		}
+		reg, err := loadLinkRegistry(baseDir)
+		if err != nil {
+			return ownerInfo{}, err
+		}
+		info := resolveBinLink(baseDir, reg, filepath.Join(binDir, filepath.Base(abs)), dir)
+		if info.app == "" {
+			break
+		}
+		if info.file == "" {
+			return ownerInfo{}, fmt.Errorf("%s is a dangling link of %s; run 'lav doctor'", abs, info.app)
+		}
+		owner, err := fileOwner(baseDir, info.file)
+		if err != nil {
+			return ownerInfo{}, err
+		}
+		owner.path, owner.link = abs, info.link
+		return owner, nil
+	}
+	return fileOwner(baseDir, abs)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWhichBin_ResolvesFile(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go", "gofmt")
+	installTestVersion(t, baseDir, "go", "1.23.0", "go", "gofmt")
+	if err := switchVersion(baseDir, "go", "1.22.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := whichBin(baseDir, "gofmt", t.TempDir())
+	if err != nil || len(links) != 1 {
+		t.Fatalf("expected one link, got %v %v", links, err)
+	}
+	want := filepath.Join(realPath(baseDir), "go", "1.22.0", "bin", "gofmt")
+	if info := links[0]; info.app != "go" || info.version != "1.22.0" || info.source != "current" || info.file != want {
+		t.Errorf("unexpected resolution: %+v", info)
+	}
+
+	// A dangling link reports the version it was meant for
+	os.RemoveAll(filepath.Join(baseDir, "go", "1.22.0"))
+	links, _ = whichBin(baseDir, "gofmt", t.TempDir())
+	if info := links[0]; info.file != "" || info.version != "1.22.0" {
+		t.Errorf("unexpected resolution of a dangling link: %+v", info)
+	}
+}
+
+func TestShadowingExecutable(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "gofmt")
+	binDir := filepath.Join(home, ".local", "bin")
+	other := t.TempDir()
+	os.WriteFile(filepath.Join(other, "gofmt"), []byte("other"), 0755)
+
+	links, err := whichBin(baseDir, "gofmt", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+other)
+	if got := shadowingExecutable("gofmt", links); got != "" {
+		t.Errorf("expected the link to come first, got %s", got)
+	}
+	t.Setenv("PATH", other+string(os.PathListSeparator)+binDir)
+	if got := shadowingExecutable("gofmt", links); got != filepath.Join(other, "gofmt") {
+		t.Errorf("expected %s to shadow the link, got %q", filepath.Join(other, "gofmt"), got)
+	}
+}
+
+func TestPathOwner(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go", "gofmt")
+	installTestVersion(t, baseDir, "go", "1.23.0", "go", "gofmt")
+	dir := t.TempDir()
+
+	tests := []struct {
+		path, version, file, link string
+	}{
+		{filepath.Join(baseDir, "go", "1.22.0", "bin", "go"), "1.22.0", "bin/go", ""},
+		{filepath.Join(baseDir, "go", "current", "bin", "gofmt"), "1.23.0", "bin/gofmt", ""},
+		{filepath.Join(baseDir, "go", "1.22.0"), "1.22.0", "", ""},
+		{filepath.Join(home, ".local", "bin", "gofmt"), "1.23.0", "bin/gofmt", filepath.Join(home, ".local", "bin", "gofmt")},
+	}
+	for _, tt := range tests {
+		owner, err := pathOwner(baseDir, tt.path, dir)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.path, err)
+			continue
+		}
+		if owner.app != "go" || owner.version != tt.version || owner.file != tt.file || owner.link != tt.link {
+			t.Errorf("%s: unexpected owner %+v", tt.path, owner)
+		}
+	}
+
+	// A symlink elsewhere that points into the root
+	alias := filepath.Join(t.TempDir(), "go")
+	os.Symlink(filepath.Join(baseDir, "go", "1.22.0", "bin", "go"), alias)
+	if owner, err := pathOwner(baseDir, alias, dir); err != nil || owner.version != "1.22.0" || owner.path != alias {
+		t.Errorf("unexpected owner of a symlink into the root: %+v %v", owner, err)
+	}
+
+	os.MkdirAll(filepath.Join(baseDir, ".cache", "sha256"), 0755)
+	if _, err := pathOwner(baseDir, filepath.Join(baseDir, ".cache", "sha256"), dir); err == nil || !strings.Contains(err.Error(), ".cache") {
+		t.Errorf("expected an error for lav's own directory, got %v", err)
+	}
+	outside := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(outside, nil, 0644)
+	if _, err := pathOwner(baseDir, outside, dir); err == nil {
+		t.Error("expected an error for a path outside the root")
+	}
+}
+
+func TestWhichBin_Shim(t *testing.T) {
+	home := setupTestEnv(t)
+	configPath := filepath.Join(home, "config.toml")
+	os.WriteFile(configPath, []byte("[apps.go]\nmode = \"shim\"\n"), 0644)
+
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.0", "go")
+
+	// Shims run the version picked in the working directory
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, ".lav-version"), []byte("go 1.22.0\n"), 0644)
+	links, err := whichBin(baseDir, "go", project)
+	if err != nil || len(links) != 1 {
+		t.Fatalf("expected one link, got %v %v", links, err)
+	}
+	info := links[0]
+	if !info.shim || info.version != "1.22.0" || info.source != filepath.Join(project, ".lav-version") {
+		t.Errorf("unexpected resolution: %+v", info)
+	}
+	if info.file != filepath.Join(baseDir, "go", "1.22.0", "bin", "go") {
+		t.Errorf("unexpected file: %s", info.file)
+	}
+
+	owner, err := pathOwner(baseDir, filepath.Join(home, ".local", "bin", "go"), project)
+	if err != nil || owner.version != "1.22.0" {
+		t.Errorf("unexpected owner: %+v %v", owner, err)
+	}
+}
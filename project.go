@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Project version files declare the versions a project expects. .lav.toml
+// holds `app = "version"` pairs; .lav-version holds one `app version` pair
+// per line.
+const (
+	projectTOMLFile    = ".lav.toml"
+	projectVersionFile = ".lav-version"
+)
+
+// readProjectFile parses a project version file and returns its versions
+// keyed by app.
+func readProjectFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	versions := make(map[string]string)
+
+	if filepath.Base(path) == projectTOMLFile {
+		tables, err := parseTOML(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for app, value := range tables[""] {
+			version, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: version of %s must be a string", path, app)
+			}
+			versions[app] = version
+		}
+		return versions, nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: expected \"<app> <version>\", got %q", path, line)
+		}
+		versions[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// findProjectFiles returns the project version files in dir and its parent
+// directories, nearest first. Within a directory .lav.toml takes precedence
+// over .lav-version.
+func findProjectFiles(dir string) []string {
+	var files []string
+	for {
+		for _, name := range []string{projectTOMLFile, projectVersionFile} {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				files = append(files, path)
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return files
+		}
+		dir = parent
+	}
+}
+
+// projectVersion is a version set by a project version file.
+type projectVersion struct {
+	version string
+	file    string
+}
+
+// projectVersions returns the versions set by the project version files in
+// dir and its parents, keyed by app. The nearest file naming an app wins.
+func projectVersions(dir string) (map[string]projectVersion, error) {
+	versions := make(map[string]projectVersion)
+	for _, file := range findProjectFiles(dir) {
+		fileVersions, err := readProjectFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for app, version := range fileVersions {
+			if _, ok := versions[app]; !ok {
+				versions[app] = projectVersion{version: version, file: file}
+			}
+		}
+	}
+	return versions, nil
+}
+
+// projectReferences returns every version referenced by the project version
+// files of the configured projects and of the working directory, keyed by
+// app. Unreadable files are skipped.
+func projectReferences(cfg config) map[string]map[string]bool {
+	var files []string
+	for _, dir := range cfg.Projects {
+		dir = expandHome(dir)
+		for _, name := range []string{projectTOMLFile, projectVersionFile} {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		files = append(files, findProjectFiles(cwd)...)
+	}
+
+	refs := make(map[string]map[string]bool)
+	for _, file := range files {
+		versions, err := readProjectFile(file)
+		if err != nil {
+			continue
+		}
+		for app, version := range versions {
+			if refs[app] == nil {
+				refs[app] = make(map[string]bool)
+			}
+			refs[app][version] = true
+		}
+	}
+
+	return refs
+}
+
+// setProjectVersion sets the version of app in the project version file in
+// dir, creating .lav.toml if dir has neither file. Other lines, including
+// comments, are kept as they are. It returns the path of the file written.
+func setProjectVersion(dir, app, version string) (string, error) {
+	path := filepath.Join(dir, projectTOMLFile)
+	entry := fmt.Sprintf("%s = %q", app, version)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(dir, projectVersionFile)); err == nil {
+			path = filepath.Join(dir, projectVersionFile)
+			entry = app + " " + version
+		}
+	}
+
+	// Validate the existing file before rewriting it
+	if _, err := readProjectFile(path); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	replaced := false
+	insertAt := len(lines)
+	for i, line := range lines {
+		// Top-level keys must come before the first table
+		if strings.HasPrefix(strings.TrimSpace(line), "[") {
+			insertAt = i
+			break
+		}
+		if projectLineApp(path, line) == app {
+			lines[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		lines = slices.Insert(lines, insertAt, entry)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp.Name(), path)
+}
+
+// projectLineApp returns the app a line of a project version file sets, or
+// "" for blank lines and comments.
+func projectLineApp(path, line string) string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ""
+	}
+	if filepath.Base(path) == projectTOMLFile {
+		key, _, ok := strings.Cut(line, "=")
+		if !ok {
+			return ""
+		}
+		return strings.Trim(strings.TrimSpace(key), `"`)
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// versionEnvVar returns the environment variable that selects the version
+// of app, e.g. LAV_GO_VERSION or LAV_GODOT_MONO_VERSION.
+func versionEnvVar(app string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, app)
+	return "LAV_" + name + "_VERSION"
+}
+
+// resolveActiveVersion returns the version of app in effect in dir and
+// where it comes from: the LAV_<APP>_VERSION environment variable ("lav
+// shell" when a lav shell session set it), the nearest project version file
+// naming app, or the global current version. The variable is ignored when
+// the lav init hook set it from a project file, so the file is reported.
+// Versions from the environment and project files may be constraints.
+func resolveActiveVersion(baseDir, app, dir string) (string, string, error) {
+	spec, source := "", ""
+	if env := versionEnvVar(app); os.Getenv(env) != "" && !inEnvList("LAV_HOOK", app) {
+		spec, source = os.Getenv(env), env
+		if inEnvList("LAV_SHELL", app) {
+			source = "lav shell"
+		}
+	} else {
+		versions, err := projectVersions(dir)
+		if err != nil {
+			return "", "", err
+		}
+		if pv, ok := versions[app]; ok {
+			spec, source = pv.version, pv.file
+		}
+	}
+
+	if spec == "" {
+		current, err := getCurrentVersion(baseDir, app)
+		if err != nil {
+			return "", "", err
+		}
+		if current == "" {
+			return "", "", fmt.Errorf("no version of %s is selected", app)
+		}
+		return current, "current", nil
+	}
+
+	versions, err := listVersions(baseDir, app)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", err
+	}
+	version, err := resolveVersion(app, versions, spec, false)
+	if err != nil {
+		return "", "", fmt.Errorf("%s (from %s)", err, source)
+	}
+	return version, source, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadProjectFile(t *testing.T) {
+	dir := t.TempDir()
+
+	toml := filepath.Join(dir, projectTOMLFile)
+	os.WriteFile(toml, []byte("go = \"1.23.4\"\ngodot = \"4.5.1\" # editor\n"), 0644)
+	versions, err := readProjectFile(toml)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if versions["go"] != "1.23.4" || versions["godot"] != "4.5.1" {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+
+	plain := filepath.Join(dir, projectVersionFile)
+	os.WriteFile(plain, []byte("# toolchains\ngo 1.22.0\n"), 0644)
+	versions, err = readProjectFile(plain)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if versions["go"] != "1.22.0" {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+
+	os.WriteFile(plain, []byte("go\n"), 0644)
+	if _, err := readProjectFile(plain); err == nil {
+		t.Error("expected error for malformed line")
+	}
+}
+
+func TestFindProjectFiles(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	os.MkdirAll(sub, 0755)
+	os.WriteFile(filepath.Join(root, projectVersionFile), []byte("go 1.22.0\n"), 0644)
+	os.WriteFile(filepath.Join(root, "a", projectTOMLFile), []byte("go = \"1.23.0\"\n"), 0644)
+
+	files := findProjectFiles(sub)
+	if len(files) < 2 {
+		t.Fatalf("expected at least 2 files, got %v", files)
+	}
+	// Nearest file first
+	if files[0] != filepath.Join(root, "a", projectTOMLFile) {
+		t.Errorf("expected nearest file first, got %v", files)
+	}
+	if files[1] != filepath.Join(root, projectVersionFile) {
+		t.Errorf("expected parent file second, got %v", files)
+	}
+}
+
+func TestVersionEnvVar(t *testing.T) {
+	for app, want := range map[string]string{
+		"go":         "LAV_GO_VERSION",
+		"godot-mono": "LAV_GODOT_MONO_VERSION",
+		"node22":     "LAV_NODE22_VERSION",
+	} {
+		if got := versionEnvVar(app); got != want {
+			t.Errorf("versionEnvVar(%q) = %q, want %q", app, got, want)
+		}
+	}
+}
+
+func TestResolveActiveVersion(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("LAV_GO_VERSION", "")
+	baseDir := t.TempDir()
+	for _, version := range []string{"1.22.5", "1.23.0", "1.23.4"} {
+		installTestVersion(t, baseDir, "go", version, "go")
+	}
+	switchVersion(baseDir, "go", "1.22.5", false)
+
+	project := t.TempDir()
+	sub := filepath.Join(project, "cmd")
+	os.MkdirAll(sub, 0755)
+
+	// No project file: the global current version
+	version, source, err := resolveActiveVersion(baseDir, "go", sub)
+	if err != nil || version != "1.22.5" || source != "current" {
+		t.Errorf("expected current 1.22.5, got %s from %s (%v)", version, source, err)
+	}
+
+	// The nearest project file, which may hold a constraint
+	projectFile := filepath.Join(project, projectTOMLFile)
+	os.WriteFile(projectFile, []byte(`go = "1.23"`+"\n"), 0644)
+	version, source, err = resolveActiveVersion(baseDir, "go", sub)
+	if err != nil || version != "1.23.4" || source != projectFile {
+		t.Errorf("expected 1.23.4 from %s, got %s from %s (%v)", projectFile, version, source, err)
+	}
+
+	// The environment wins
+	t.Setenv("LAV_GO_VERSION", "1.23.0")
+	version, source, err = resolveActiveVersion(baseDir, "go", sub)
+	if err != nil || version != "1.23.0" || source != "LAV_GO_VERSION" {
+		t.Errorf("expected 1.23.0 from LAV_GO_VERSION, got %s from %s (%v)", version, source, err)
+	}
+
+	t.Setenv("LAV_GO_VERSION", "1.24")
+	if _, _, err := resolveActiveVersion(baseDir, "go", sub); err == nil {
+		t.Error("expected error for a version that is not installed")
+	}
+}
+
+func TestSetProjectVersion(t *testing.T) {
+	dir := t.TempDir()
+
+	// Creates .lav.toml
+	path, err := setProjectVersion(dir, "go", "1.23.4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(dir, projectTOMLFile) {
+		t.Errorf("expected .lav.toml, got %s", path)
+	}
+
+	// Updates in place, keeping comments and tables
+	os.WriteFile(path, []byte("# toolchain\ngo = \"1.22.0\"\n\n[tools]\ngodot = \"x\"\n"), 0644)
+	if _, err := setProjectVersion(dir, "go", "1.23.4"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := setProjectVersion(dir, "godot", "4.5.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	want := "# toolchain\ngo = \"1.23.4\"\n\ngodot = \"4.5.1\"\n[tools]\ngodot = \"x\"\n"
+	if string(data) != want {
+		t.Errorf("unexpected file:\n%s\nwant:\n%s", data, want)
+	}
+
+	// An existing .lav-version is used when there is no .lav.toml
+	other := t.TempDir()
+	versionFile := filepath.Join(other, projectVersionFile)
+	os.WriteFile(versionFile, []byte("go 1.22.0\nnode 22\n"), 0644)
+	if path, err := setProjectVersion(other, "go", "1.23.4"); err != nil || path != versionFile {
+		t.Fatalf("expected %s, got %s (%v)", versionFile, path, err)
+	}
+	data, _ = os.ReadFile(versionFile)
+	if string(data) != "go 1.23.4\nnode 22\n" {
+		t.Errorf("unexpected file: %q", data)
+	}
+}
+
+func TestProjectVersions(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	os.MkdirAll(sub, 0755)
+	os.WriteFile(filepath.Join(root, projectTOMLFile), []byte("go = \"1.22.0\"\nnode = \"22\"\n"), 0644)
+	os.WriteFile(filepath.Join(sub, projectVersionFile), []byte("go 1.23.4\n"), 0644)
+
+	versions, err := projectVersions(sub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := versions["go"]; v.version != "1.23.4" || v.file != filepath.Join(sub, projectVersionFile) {
+		t.Errorf("nearest file should win for go, got %+v", v)
+	}
+	if v := versions["node"]; v.version != "22" || v.file != filepath.Join(root, projectTOMLFile) {
+		t.Errorf("unexpected node version: %+v", v)
+	}
+}
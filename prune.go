@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// prunePolicy decides which versions of an app lav prune removes.
+type prunePolicy struct {
+	keep      int           // keep the newest keep versions, 0 if unset
+	olderThan time.Duration // only remove versions older than this, 0 if unset
+}
+
+func (p prunePolicy) isSet() bool {
+	return p.keep > 0 || p.olderThan > 0
+}
+
+// pruneCandidate is a version selected for removal.
+type pruneCandidate struct {
+	app     string
+	version string
+	size    int64
+	reason  string // why the policy selects it, e.g. "not among the 3 newest"
+}
+
+// appPrunePolicy returns the policy for app: flags take precedence over the
+// app's settings in the config file.
+func appPrunePolicy(flags prunePolicy, ac appConfig) (prunePolicy, error) {
+	policy := flags
+	if policy.keep == 0 {
+		policy.keep = ac.Keep
+	}
+	if policy.olderThan == 0 && ac.OlderThan != "" {
+		d, err := parseAge(ac.OlderThan)
+		if err != nil {
+			return policy, err
+		}
+		policy.olderThan = d
+	}
+	return policy, nil
+}
+
+// planPrune returns the versions of app that policy removes. The current
+// version and versions in protected are always kept.
+func planPrune(baseDir, app string, policy prunePolicy, protected map[string]bool, now time.Time) ([]pruneCandidate, error) {
+	versions, err := listVersions(baseDir, app)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := getCurrentVersion(baseDir, app)
+	if err != nil {
+		return nil, err
+	}
+
+	// versions is sorted oldest first
+	kept := make(map[string]bool)
+	if policy.keep > 0 {
+		for i := len(versions) - 1; i >= 0 && i >= len(versions)-policy.keep; i-- {
+			kept[versions[i]] = true
+		}
+	}
+
+	var candidates []pruneCandidate
+	for _, version := range versions {
+		if version == current || protected[version] || kept[version] {
+			continue
+		}
+
+		var reasons []string
+		if policy.keep > 0 {
+			reasons = append(reasons, fmt.Sprintf("not among the %d newest", policy.keep))
+		}
+		versionDir := filepath.Join(baseDir, app, version)
+		if policy.olderThan > 0 {
+			installedAt, err := versionInstalledAt(baseDir, app, version)
+			if err != nil {
+				return nil, err
+			}
+			if now.Sub(installedAt) < policy.olderThan {
+				continue
+			}
+			reasons = append(reasons, "older than "+formatAge(policy.olderThan))
+		}
+
+		_, size, err := versionStats(versionDir)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, pruneCandidate{app: app, version: version, size: size, reason: strings.Join(reasons, ", ")})
+	}
+
+	return candidates, nil
+}
+
+// planPruneApps plans pruning for each app in apps. When apps is a single
+// explicitly named app it must have a retention policy; otherwise apps
+// without one are skipped. Pinned versions and the versions that project
+// version files resolve to are protected.
+func planPruneApps(baseDir string, apps []string, explicit bool, flags prunePolicy, cfg config) ([]pruneCandidate, error) {
+	refs := projectReferences(cfg)
+
+	var candidates []pruneCandidate
+	for _, app := range apps {
+		ac := cfg.Apps[app]
+		policy, err := appPrunePolicy(flags, ac)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", app, err)
+		}
+		if !policy.isSet() {
+			if explicit {
+				return nil, fmt.Errorf("no retention policy for %s; use --keep or --older-than, or set one in the config", app)
+			}
+			continue
+		}
+
+		installed, err := listVersions(baseDir, app)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		protected := make(map[string]bool)
+		for _, version := range ac.Pinned {
+			protected[version] = true
+		}
+		// Project files may name a constraint; protect what it resolves to
+		for spec := range refs[app] {
+			protected[spec] = true
+			if version, err := resolveVersion(app, installed, spec, false); err == nil {
+				protected[version] = true
+			}
+		}
+
+		appCandidates, err := planPrune(baseDir, app, policy, protected, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, appCandidates...)
+	}
+
+	return candidates, nil
+}
+
+// parseAge parses a duration such as "90d", "2w" or "36h".
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration: %s", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration: %s", s)
+	}
+	return d, nil
+}
+
+// formatAge formats a duration parsed by parseAge, in days when it is a
+// whole number of them.
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d >= day && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
+
+// versionInstalledAt returns when version was installed: the time in its
+// install metadata, or the modification time of its directory for versions
+// installed before lav recorded metadata.
+func versionInstalledAt(baseDir, app, version string) (time.Time, error) {
+	meta, err := readVersionMeta(baseDir, app, version)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if meta != nil {
+		return meta.InstalledAt, nil
+	}
+	info, err := os.Stat(filepath.Join(baseDir, app, version))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// formatSize formats a byte count for humans, e.g. "1.5 GB".
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
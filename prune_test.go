@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func setupPruneApp(t *testing.T, baseDir string, versions []string, current string) {
+	t.Helper()
+	for i, version := range versions {
+		dir := filepath.Join(baseDir, "app", version)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "data"), make([]byte, 100*(i+1)), 0644)
+	}
+	os.Symlink(current, filepath.Join(baseDir, "app", "current"))
+}
+
+func candidateVersions(candidates []pruneCandidate) []string {
+	var versions []string
+	for _, c := range candidates {
+		versions = append(versions, c.version)
+	}
+	return versions
+}
+
+func TestPlanPrune_Keep(t *testing.T) {
+	baseDir := t.TempDir()
+	setupPruneApp(t, baseDir, []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0"}, "1.0.0")
+
+	candidates, err := planPrune(baseDir, "app", prunePolicy{keep: 2}, nil, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 1.2.0 and 1.3.0 are kept as newest, 1.0.0 as current
+	got := candidateVersions(candidates)
+	if len(got) != 1 || got[0] != "1.1.0" {
+		t.Errorf("expected [1.1.0], got %v", got)
+	}
+	if candidates[0].size != 200 {
+		t.Errorf("expected size 200, got %d", candidates[0].size)
+	}
+	if candidates[0].reason != "not among the 2 newest" {
+		t.Errorf("unexpected reason %q", candidates[0].reason)
+	}
+}
+
+func TestPlanPrune_OlderThanAndProtected(t *testing.T) {
+	baseDir := t.TempDir()
+	setupPruneApp(t, baseDir, []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0"}, "1.3.0")
+
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		os.Chtimes(filepath.Join(baseDir, "app", version), old, old)
+	}
+
+	policy := prunePolicy{olderThan: 90 * 24 * time.Hour}
+	protected := map[string]bool{"1.0.0": true}
+	candidates, err := planPrune(baseDir, "app", policy, protected, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := candidateVersions(candidates)
+	if len(got) != 1 || got[0] != "1.1.0" {
+		t.Errorf("expected [1.1.0], got %v", got)
+	}
+	if len(candidates) == 1 && candidates[0].reason != "older than 90d" {
+		t.Errorf("unexpected reason %q", candidates[0].reason)
+	}
+}
+
+func TestPlanPruneApps(t *testing.T) {
+	baseDir := t.TempDir()
+	setupPruneApp(t, baseDir, []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0"}, "1.3.0")
+
+	projectDir := t.TempDir()
+	os.WriteFile(filepath.Join(projectDir, projectTOMLFile), []byte(`app = "1.1.0"`+"\n"), 0644)
+
+	cfg := config{
+		Projects: []string{projectDir},
+		Apps:     map[string]appConfig{"app": {Keep: 1, Pinned: []string{"1.0.0"}}},
+	}
+
+	// Policy comes from the config; pinned and project versions are kept
+	candidates, err := planPruneApps(baseDir, []string{"app"}, true, prunePolicy{}, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := candidateVersions(candidates)
+	if len(got) != 1 || got[0] != "1.2.0" {
+		t.Errorf("expected [1.2.0], got %v", got)
+	}
+
+	// An explicitly named app needs a policy
+	if _, err := planPruneApps(baseDir, []string{"app"}, true, prunePolicy{}, config{}); err == nil {
+		t.Error("expected error without a retention policy")
+	}
+	// Apps without a policy are skipped when pruning everything
+	if candidates, err := planPruneApps(baseDir, []string{"app"}, false, prunePolicy{}, config{}); err != nil || len(candidates) != 0 {
+		t.Errorf("expected no candidates, got %v (%v)", candidates, err)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90d": 90 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+	}
+	for input, want := range tests {
+		got, err := parseAge(input)
+		if err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := parseAge("soon"); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		512:                    "512 B",
+		1536:                   "1.5 KB",
+		3 * 1024 * 1024 * 1024: "3.0 GB",
+	}
+	for size, want := range tests {
+		if got := formatSize(size); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
+
+func TestPlanPruneApps_ProjectConstraint(t *testing.T) {
+	baseDir := t.TempDir()
+	setupPruneApp(t, baseDir, []string{"1.9.0", "1.10.0"}, "1.10.0")
+
+	projectDir := t.TempDir()
+	os.WriteFile(filepath.Join(projectDir, projectTOMLFile), []byte(`app = "1.9"`+"\n"), 0644)
+	cfg := config{Projects: []string{projectDir}}
+
+	// "1.9" resolves to 1.9.0, which is kept
+	candidates, err := planPruneApps(baseDir, []string{"app"}, true, prunePolicy{keep: 1}, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := candidateVersions(candidates); len(got) != 0 {
+		t.Errorf("expected no candidates, got %v", got)
+	}
+}
+
+func TestPlanPrune_InstallMetadata(t *testing.T) {
+	baseDir := t.TempDir()
+	setupPruneApp(t, baseDir, []string{"1.0.0", "1.1.0", "1.2.0"}, "1.2.0")
+
+	// 1.0.0 was installed long ago but its directory was written to since;
+	// 1.1.0 has an old directory but was installed recently
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	writeVersionMeta(baseDir, "app", "1.0.0", versionMeta{Source: "/src", InstalledAt: old})
+	writeVersionMeta(baseDir, "app", "1.1.0", versionMeta{Source: "/src", InstalledAt: time.Now()})
+	os.Chtimes(filepath.Join(baseDir, "app", "1.1.0"), old, old)
+
+	candidates, err := planPrune(baseDir, "app", prunePolicy{olderThan: 90 * 24 * time.Hour}, nil, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := candidateVersions(candidates)
+	if len(got) != 1 || got[0] != "1.0.0" {
+		t.Fatalf("expected [1.0.0], got %v", got)
+	}
+	// The metadata lav keeps in the version is not counted
+	if candidates[0].size != 100 {
+		t.Errorf("expected size 100, got %d", candidates[0].size)
+	}
+}
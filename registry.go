@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// linkRegistry records which app owns each bin link lav created. It is
+// stored in <base>/.registry/links.json.
+type linkRegistry struct {
+	// Links maps the absolute path of each bin link to its owning app
+	Links map[string]string `json:"links"`
+	// Alternatives maps executable names shared by several apps to the
+	// apps registered as their providers
+	Alternatives map[string]*alternativeGroup `json:"alternatives,omitempty"`
+}
+
+func registryPath(baseDir string) string {
+	return filepath.Join(baseDir, ".registry", "links.json")
+}
+
+func registryLockPath(baseDir string) string {
+	return filepath.Join(baseDir, ".registry", "lock")
+}
+
+// lockLinkRegistry takes an exclusive lock on the link registry, waiting
+// while another lav process holds it. Commands that change the registry
+// hold the lock from loading it until it is saved, so that concurrent
+// commands do not lose each other's changes. Readers need no lock since
+// the registry is replaced atomically. The returned function releases it.
+func lockLinkRegistry(baseDir string) (func(), error) {
+	path := registryLockPath(baseDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create registry directory: %w", err)
+	}
+	unlock, err := lockPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock link registry: %w", err)
+	}
+	return unlock, nil
+}
+
+// updateLinkRegistry loads the registry under its lock, lets change modify
+// it and saves it.
+func updateLinkRegistry(baseDir string, change func(reg *linkRegistry) error) error {
+	unlock, err := lockLinkRegistry(baseDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		return err
+	}
+	if err := change(reg); err != nil {
+		return err
+	}
+	return reg.save(baseDir)
+}
+
+// loadLinkRegistry reads the link registry. A missing registry is empty.
+func loadLinkRegistry(baseDir string) (*linkRegistry, error) {
+	reg := &linkRegistry{Links: make(map[string]string), Alternatives: make(map[string]*alternativeGroup)}
+
+	data, err := os.ReadFile(registryPath(baseDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return reg, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, reg); err != nil {
+		return nil, fmt.Errorf("invalid link registry %s: %w", registryPath(baseDir), err)
+	}
+	if reg.Links == nil {
+		reg.Links = make(map[string]string)
+	}
+	if reg.Alternatives == nil {
+		reg.Alternatives = make(map[string]*alternativeGroup)
+	}
+	return reg, nil
+}
+
+// save writes the registry to a temporary file and renames it into place,
+// so readers never see a partially written registry. Callers hold the lock
+// of lockLinkRegistry.
+func (r *linkRegistry) save(baseDir string) error {
+	path := registryPath(baseDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".links-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// owner returns the app that owns link, or "" if lav does not manage it.
+// Links created before the registry existed are attributed to the app
+// their target points into.
+func (r *linkRegistry) owner(baseDir, link string) string {
+	if app, ok := r.Links[link]; ok {
+		return app
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return ""
+	}
+	target, err := readLinkTarget(link)
+	if err != nil {
+		return ""
+	}
+
+	for _, root := range []string{baseDir, realPath(baseDir)} {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(absRoot, target)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		app, _, _ := strings.Cut(rel, string(filepath.Separator))
+		if validateName("app", app) == nil {
+			return app
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestLinkRegistry_SaveLoad(t *testing.T) {
+	baseDir := t.TempDir()
+
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reg.Links) != 0 {
+		t.Errorf("expected empty registry, got %v", reg.Links)
+	}
+
+	reg.Links["/home/u/.local/bin/go"] = "go"
+	if err := reg.save(baseDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reg, err = loadLinkRegistry(baseDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reg.Links["/home/u/.local/bin/go"] != "go" {
+		t.Errorf("unexpected registry: %v", reg.Links)
+	}
+}
+
+func TestUpdateLinkRegistry_Concurrent(t *testing.T) {
+	baseDir := t.TempDir()
+
+	// Each update loads, changes and saves the registry; without the lock
+	// concurrent updates would overwrite each other's links
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := updateLinkRegistry(baseDir, func(reg *linkRegistry) error {
+				reg.Links[fmt.Sprintf("/bin/tool%d", i)] = fmt.Sprintf("app%d", i)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reg.Links) != 20 {
+		t.Errorf("expected 20 links, got %d: %v", len(reg.Links), reg.Links)
+	}
+}
+
+func TestLinkRegistry_Owner(t *testing.T) {
+	baseDir := t.TempDir()
+	binDir := t.TempDir()
+	reg := &linkRegistry{Links: map[string]string{filepath.Join(binDir, "node"): "node"}}
+
+	// Links created before the registry are attributed by their target
+	os.Symlink(filepath.Join(baseDir, "python", "current", "bin", "python"), filepath.Join(binDir, "python"))
+	os.Symlink("/usr/bin/env", filepath.Join(binDir, "env"))
+	os.WriteFile(filepath.Join(binDir, "script"), []byte("mine"), 0755)
+
+	for name, want := range map[string]string{"node": "node", "python": "python", "env": "", "script": "", "missing": ""} {
+		if got := reg.owner(baseDir, filepath.Join(binDir, name)); got != want {
+			t.Errorf("owner of %s: expected %q, got %q", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// validateName rejects app and version names that would resolve outside
+// their directory in the lav tree.
+func validateName(kind, name string) error {
+	if name == "" || name == "." || name == ".." || strings.HasPrefix(name, ".") ||
+		strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+		return fmt.Errorf("invalid %s name: %q", kind, name)
+	}
+	return nil
+}
+
+// validateVersionName checks version as validateName does and also rejects
+// "current", the name of the symlink to the current version.
+func validateVersionName(version string) error {
+	if err := validateName("version", version); err != nil || version == "current" {
+		return fmt.Errorf("invalid version name: %q", version)
+	}
+	return nil
+}
+
+// removeVersion deletes version of appName. The current version is only
+// removed with force, in which case the current symlink and the app's bin
+// links are removed as well. It returns the bin links that were removed.
+func removeVersion(baseDir, appName, version string, force bool) ([]string, error) {
+	if err := validateName("app", appName); err != nil {
+		return nil, err
+	}
+	if err := validateVersionName(version); err != nil {
+		return nil, err
+	}
+
+	appDir := filepath.Join(baseDir, appName)
+	versionDir := filepath.Join(appDir, version)
+	if info, err := os.Lstat(versionDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("version %s does not exist for %s", version, appName)
+	}
+
+	current, err := getCurrentVersion(baseDir, appName)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	if current == version {
+		if !force {
+			return nil, fmt.Errorf("%s %s is the current version; switch to another version first or use --force", appName, version)
+		}
+
+		if removed, err = removeBinLinks(baseDir, appName); err != nil {
+			return removed, err
+		}
+		if err := os.Remove(filepath.Join(appDir, "current")); err != nil {
+			return removed, fmt.Errorf("failed to remove current symlink: %w", err)
+		}
+	}
+
+	if err := removeTree(baseDir, versionDir, appName, version); err != nil {
+		return removed, err
+	}
+
+	// Drop the app directory once its last version is gone
+	if versions, err := listVersions(baseDir, appName); err == nil && len(versions) == 0 {
+		os.Remove(appDir)
+	}
+
+	return removed, nil
+}
+
+// removeApp deletes every version of appName along with its current symlink
+// and bin links. An app with a current version is only removed with force.
+// It returns the bin links that were removed.
+func removeApp(baseDir, appName string, force bool) ([]string, error) {
+	if err := validateName("app", appName); err != nil {
+		return nil, err
+	}
+
+	appDir := filepath.Join(baseDir, appName)
+	if info, err := os.Lstat(appDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("app %s is not installed", appName)
+	}
+
+	current, err := getCurrentVersion(baseDir, appName)
+	if err != nil {
+		return nil, err
+	}
+	if current != "" && !force {
+		return nil, fmt.Errorf("%s %s is the current version; use --force to remove all versions of %s", appName, current, appName)
+	}
+
+	removed, err := removeBinLinks(baseDir, appName)
+	if err != nil {
+		return removed, err
+	}
+
+	if err := removeTree(baseDir, appDir, appName, "all"); err != nil {
+		return removed, err
+	}
+	return removed, forgetProvider(baseDir, appName)
+}
+
+// removeTree deletes dir by first renaming it into the staging directory,
+// so it disappears from the lav tree atomically even if the deletion itself
+// is interrupted.
+func removeTree(baseDir, dir, appName, version string) error {
+	trash, err := newStagingDir(baseDir, appName, version)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(trash)
+
+	if err := os.Rename(dir, filepath.Join(trash, filepath.Base(dir))); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+// removeBinLinks removes the symlinks in the bin directories that appName
+// owns, handing executables shared through alternatives to the next
+// provider. Other files are never touched.
+func removeBinLinks(baseDir, appName string) ([]string, error) {
+	return reconcileBinLinks(baseDir, appName, nil, false)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveVersion(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.0", "go")
+
+	// Non-current version is removed without touching links
+	removed, err := removeVersion(baseDir, "go", "1.22.0", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("expected no links removed, got %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "go", "1.22.0")); !os.IsNotExist(err) {
+		t.Error("version directory should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(home, ".local", "bin", "go")); err != nil {
+		t.Error("bin link of the current version should be kept")
+	}
+}
+
+func TestRemoveVersion_CurrentRequiresForce(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	installTestVersion(t, baseDir, "go", "1.23.0", "go", "gofmt")
+
+	if _, err := removeVersion(baseDir, "go", "1.23.0", false); err == nil {
+		t.Fatal("expected error when removing the current version")
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "go", "1.23.0")); err != nil {
+		t.Error("current version should be kept without --force")
+	}
+
+	// Unrelated files in the bin directory are never touched
+	binDir := filepath.Join(home, ".local", "bin")
+	os.WriteFile(filepath.Join(binDir, "script"), []byte("mine"), 0755)
+	os.Symlink("/usr/bin/env", filepath.Join(binDir, "env"))
+
+	removed, err := removeVersion(baseDir, "go", "1.23.0", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("expected 2 links removed, got %v", removed)
+	}
+
+	for _, name := range []string{"go", "gofmt"} {
+		if _, err := os.Lstat(filepath.Join(binDir, name)); !os.IsNotExist(err) {
+			t.Errorf("bin link %s should be removed", name)
+		}
+	}
+	for _, name := range []string{"script", "env"} {
+		if _, err := os.Lstat(filepath.Join(binDir, name)); err != nil {
+			t.Errorf("unrelated file %s should be kept", name)
+		}
+	}
+
+	// The now-empty app is gone as well
+	if apps, _ := listApps(baseDir); len(apps) != 0 {
+		t.Errorf("expected no apps, got %v", apps)
+	}
+}
+
+func TestRemoveApp(t *testing.T) {
+	home := setupTestEnv(t)
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.0", "go")
+	installTestVersion(t, baseDir, "godot", "4.5.1", "godot")
+
+	if _, err := removeApp(baseDir, "go", false); err == nil {
+		t.Fatal("expected error when removing an app with a current version")
+	}
+
+	if _, err := removeApp(baseDir, "go", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	apps, _ := listApps(baseDir)
+	if len(apps) != 1 || apps[0] != "godot" {
+		t.Errorf("expected only godot, got %v", apps)
+	}
+
+	binDir := filepath.Join(home, ".local", "bin")
+	if _, err := os.Lstat(filepath.Join(binDir, "go")); !os.IsNotExist(err) {
+		t.Error("go link should be removed")
+	}
+	if _, err := os.Lstat(filepath.Join(binDir, "godot")); err != nil {
+		t.Error("godot link should be kept")
+	}
+}
+
+func TestRemoveVersion_InvalidNames(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	os.MkdirAll(filepath.Join(baseDir, "go", "1.0.0"), 0755)
+
+	for _, version := range []string{"..", "current", "../go", ""} {
+		if _, err := removeVersion(baseDir, "go", version, true); err == nil {
+			t.Errorf("expected error for version %q", version)
+		}
+	}
+	if _, err := removeApp(baseDir, "..", true); err == nil {
+		t.Error("expected error for app ..")
+	}
+}
@@ -10,6 +10,7 @@ type versionSelectModel struct {
 	versions  []string
 	cursor    int
 	current   string
+	details   map[string]string // one-line description of each version
 	selected  string
 	cancelled bool
 }
@@ -29,6 +30,9 @@ func (m versionSelectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.cursor++
 			}
 		case "enter":
+			if len(m.versions) == 0 {
+				return m, nil
+			}
 			m.selected = m.versions[m.cursor]
 			return m, tea.Quit
 		case "esc", "q", "ctrl+c":
@@ -52,11 +56,16 @@ func (m versionSelectModel) View() string {
 		}
 		s += fmt.Sprintf("%s%s%s\n", cursor, v, suffix)
 	}
+	if len(m.versions) == 0 {
+		s += "  (no versions installed)\n"
+	} else if detail := m.details[m.versions[m.cursor]]; detail != "" {
+		s += fmt.Sprintf("\n  %s\n", detail)
+	}
 	s += "\n↑/↓: move  Enter: select  ESC: cancel\n"
 	return s
 }
 
-func selectVersionInteractive(app string, versions []string, current string) (string, bool, error) {
+func selectVersionInteractive(app string, versions []string, current string, details map[string]string) (string, bool, error) {
 	initialCursor := 0
 	for i, v := range versions {
 		if v == current {
@@ -70,6 +79,7 @@ func selectVersionInteractive(app string, versions []string, current string) (st
 		versions: versions,
 		cursor:   initialCursor,
 		current:  current,
+		details:  details,
 	}
 	p := tea.NewProgram(m)
 	finalModel, err := p.Run()
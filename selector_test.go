@@ -120,6 +120,35 @@ func TestVersionSelectModel_View(t *testing.T) {
 	}
 }
 
+func TestVersionSelectModel_ViewDetails(t *testing.T) {
+	m := versionSelectModel{
+		app:      "myapp",
+		versions: []string{"1.0.0", "2.0.0"},
+		cursor:   1,
+		details:  map[string]string{"1.0.0": "from one.tar.gz", "2.0.0": "from two.tar.gz"},
+	}
+
+	// Only the details of the version under the cursor are shown
+	view := m.View()
+	if !strings.Contains(view, "from two.tar.gz") || strings.Contains(view, "from one.tar.gz") {
+		t.Errorf("view should show details of the selected version:\n%s", view)
+	}
+}
+
+func TestVersionSelectModel_Empty(t *testing.T) {
+	m := versionSelectModel{app: "myapp"}
+
+	if view := m.View(); !strings.Contains(view, "no versions installed") {
+		t.Errorf("view should say there are no versions:\n%s", view)
+	}
+
+	// Enter has nothing to select
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model := updated.(versionSelectModel); model.selected != "" || cmd != nil {
+		t.Errorf("expected no selection, got %q", model.selected)
+	}
+}
+
 func TestVersionSelectModel_Init(t *testing.T) {
 	m := versionSelectModel{}
 	cmd := m.Init()
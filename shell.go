@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// shellSpecs turns the arguments of lav shell into "app@version" specs,
+// accepting both "go 1.22.0" and "go@1.22.0 node@22".
+func shellSpecs(args []string) []string {
+	if len(args) == 2 && !strings.Contains(args[0], "@") && !strings.Contains(args[1], "@") {
+		return []string{args[0] + "@" + args[1]}
+	}
+	return args
+}
+
+// detectShell returns the name of the user's shell from $SHELL, or "sh".
+func detectShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return filepath.Base(shell)
+	}
+	return "sh"
+}
+
+// shellQuote quotes s for POSIX shells and fish.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// formatExport returns the command that sets variable key to value in
+// shell. In fish, PATH is set as a list.
+func formatExport(shell, key, value string) string {
+	if shell != "fish" {
+		return fmt.Sprintf("export %s=%s", key, shellQuote(value))
+	}
+	if key == "PATH" {
+		var dirs []string
+		for _, dir := range filepath.SplitList(value) {
+			dirs = append(dirs, shellQuote(dir))
+		}
+		return "set -gx PATH " + strings.Join(dirs, " ")
+	}
+	// fish quotes a single quote inside single quotes as \'
+	return fmt.Sprintf("set -gx %s '%s'", key, strings.ReplaceAll(strings.ReplaceAll(value, `\`, `\\`), "'", `\'`))
+}
+
+// formatUnset returns the command that unsets variable key in shell.
+func formatUnset(shell, key string) string {
+	if shell == "fish" {
+		return "set -e " + key
+	}
+	return "unset " + key
+}
+
+// shellSession returns the value of LAV_SHELL, the "app@version" list of
+// session overrides, after adding selections to session.
+func shellSession(selections []execSelection, session string) string {
+	entries := strings.Fields(session)
+	for _, sel := range selections {
+		entries = slices.DeleteFunc(entries, func(entry string) bool {
+			return strings.HasPrefix(entry, sel.app+"@")
+		})
+		entries = append(entries, sel.app+"@"+sel.version)
+	}
+	return strings.Join(entries, " ")
+}
+
+// inEnvList reports whether the "app@version" list in environment variable
+// key, such as LAV_SHELL, names app.
+func inEnvList(key, app string) bool {
+	for _, entry := range strings.Fields(os.Getenv(key)) {
+		if strings.HasPrefix(entry, app+"@") {
+			return true
+		}
+	}
+	return false
+}
+
+// envChanges returns the commands that turn environment old into new in
+// shell: variables that are new or changed are exported, missing ones unset.
+func envChanges(old, new []string, shell string) []string {
+	prev := make(map[string]string)
+	for _, kv := range old {
+		key, value, _ := strings.Cut(kv, "=")
+		prev[key] = value
+	}
+	next := make(map[string]bool)
+
+	var lines []string
+	for _, kv := range new {
+		key, value, _ := strings.Cut(kv, "=")
+		next[key] = true
+		if v, ok := prev[key]; ok && v == value {
+			continue
+		}
+		lines = append(lines, formatExport(shell, key, value))
+	}
+	for _, kv := range old {
+		key, _, _ := strings.Cut(kv, "=")
+		if !next[key] {
+			next[key] = true
+			lines = append(lines, formatUnset(shell, key))
+		}
+	}
+	return lines
+}
+
+// withoutApps returns environ without what execEnv set for apps: their
+// version variables and [apps.<name>.env] variables are removed and their
+// directories dropped from PATH.
+func withoutApps(baseDir string, apps []string, environ []string, cfg config) []string {
+	if abs, err := filepath.Abs(baseDir); err == nil {
+		baseDir = abs
+	}
+
+	drop := make(map[string]bool)
+	for _, app := range apps {
+		drop[versionEnvVar(app)] = true
+		for key := range cfg.Apps[app].Env {
+			drop[key] = true
+		}
+	}
+
+	var result []string
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		if drop[key] {
+			continue
+		}
+		if key == "PATH" {
+			var kept []string
+			for _, dir := range filepath.SplitList(value) {
+				if !slices.ContainsFunc(apps, func(app string) bool {
+					return isWithin(filepath.Join(baseDir, app), dir)
+				}) {
+					kept = append(kept, dir)
+				}
+			}
+			kv = key + "=" + strings.Join(kept, string(os.PathListSeparator))
+		}
+		result = append(result, kv)
+	}
+	return result
+}
+
+// shellExports returns the commands that give the current shell the
+// environment lav exec would use for selections.
+func shellExports(baseDir string, selections []execSelection, environ []string, cfg config, shell string) []string {
+	session := append(slices.Clone(environ), "LAV_SHELL="+shellSession(selections, lookupEnv(environ, "LAV_SHELL")))
+	return envChanges(environ, execEnv(baseDir, selections, session, cfg), shell)
+}
+
+// shellUnsets returns the commands that undo lav shell --export for apps.
+func shellUnsets(baseDir string, apps []string, environ []string, cfg config, shell string) []string {
+	var session []string
+	for _, entry := range strings.Fields(lookupEnv(environ, "LAV_SHELL")) {
+		app, _, _ := strings.Cut(entry, "@")
+		if !slices.Contains(apps, app) {
+			session = append(session, entry)
+		}
+	}
+
+	env := withoutApps(baseDir, apps, environ, cfg)
+	env = slices.DeleteFunc(env, func(kv string) bool {
+		return strings.HasPrefix(kv, "LAV_SHELL=")
+	})
+	if len(session) > 0 {
+		env = append(env, "LAV_SHELL="+strings.Join(session, " "))
+	}
+	return envChanges(environ, env, shell)
+}
+
+// runShell replaces the lav process with a subshell in which selections
+// take precedence over the current versions. LAV_SHELL lists the apps
+// selected for the session.
+func runShell(baseDir string, selections []execSelection) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	environ := append(os.Environ(), "LAV_SHELL="+shellSession(selections, os.Getenv("LAV_SHELL")))
+	env := execEnv(baseDir, selections, environ, cfg)
+
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = os.Getenv("COMSPEC") // cmd.exe on Windows
+	}
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	var names []string
+	for _, sel := range selections {
+		names = append(names, sel.app+" "+sel.version)
+	}
+	fmt.Fprintf(os.Stderr, "Starting %s with %s; exit to return\n", filepath.Base(shell), strings.Join(names, ", "))
+	return execProcess(shell, []string{shell}, env)
+}
+
+// lookupEnv returns the last value of key in environ.
+func lookupEnv(environ []string, key string) string {
+	value := ""
+	for _, kv := range environ {
+		if v, ok := strings.CutPrefix(kv, key+"="); ok {
+			value = v
+		}
+	}
+	return value
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestShellSpecs(t *testing.T) {
+	if specs := shellSpecs([]string{"go", "1.22.0"}); !slices.Equal(specs, []string{"go@1.22.0"}) {
+		t.Errorf("unexpected specs: %v", specs)
+	}
+	if specs := shellSpecs([]string{"go@1.22.0", "node"}); !slices.Equal(specs, []string{"go@1.22.0", "node"}) {
+		t.Errorf("unexpected specs: %v", specs)
+	}
+	if specs := shellSpecs([]string{"go"}); !slices.Equal(specs, []string{"go"}) {
+		t.Errorf("unexpected specs: %v", specs)
+	}
+}
+
+func TestFormatExport(t *testing.T) {
+	tests := []struct {
+		shell, key, value, want string
+	}{
+		{"bash", "GOFLAGS", "-tags=it's", `export GOFLAGS='-tags=it'\''s'`},
+		{"zsh", "PATH", "/a:/b", `export PATH='/a:/b'`},
+		{"fish", "GOFLAGS", `it's a \ test`, `set -gx GOFLAGS 'it\'s a \\ test'`},
+		{"fish", "PATH", "/a:/b", `set -gx PATH '/a' '/b'`},
+	}
+	for _, tt := range tests {
+		if got := formatExport(tt.shell, tt.key, tt.value); got != tt.want {
+			t.Errorf("formatExport(%s, %s): expected %s, got %s", tt.shell, tt.key, tt.want, got)
+		}
+	}
+
+	if got := formatUnset("fish", "LAV_GO_VERSION"); got != "set -e LAV_GO_VERSION" {
+		t.Errorf("unexpected unset: %s", got)
+	}
+}
+
+func TestShellSession(t *testing.T) {
+	selections := []execSelection{{app: "go", version: "1.22.0"}}
+	if got := shellSession(selections, "go@1.23.4 node@22.1.0"); got != "node@22.1.0 go@1.22.0" {
+		t.Errorf("unexpected session: %s", got)
+	}
+	if got := shellSession(selections, ""); got != "go@1.22.0" {
+		t.Errorf("unexpected session: %s", got)
+	}
+}
+
+func TestShellExports(t *testing.T) {
+	baseDir := t.TempDir()
+	selections := []execSelection{{app: "go", version: "1.22.0"}}
+	environ := []string{"HOME=/home/me", "PATH=/usr/bin"}
+
+	lines := shellExports(baseDir, selections, environ, config{}, "bash")
+	goBin := filepath.Join(baseDir, "go", "1.22.0", "bin")
+	want := []string{
+		"export PATH='" + goBin + ":/usr/bin'",
+		"export LAV_SHELL='go@1.22.0'",
+		"export LAV_GO_VERSION='1.22.0'",
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("expected %v, got %v", want, lines)
+	}
+}
+
+func TestShellUnsets(t *testing.T) {
+	baseDir := t.TempDir()
+	goBin := filepath.Join(baseDir, "go", "1.22.0", "bin")
+	nodeBin := filepath.Join(baseDir, "node", "22.1.0", "bin")
+	environ := []string{
+		"PATH=" + goBin + ":" + nodeBin + ":/usr/bin",
+		"LAV_SHELL=go@1.22.0 node@22.1.0",
+	}
+
+	environ = append(environ, "LAV_GO_VERSION=1.22.0", "LAV_NODE_VERSION=22.1.0")
+
+	lines := shellUnsets(baseDir, []string{"go"}, environ, config{}, "bash")
+	want := []string{
+		"export PATH='" + nodeBin + ":/usr/bin'",
+		"export LAV_SHELL='node@22.1.0'",
+		"unset LAV_GO_VERSION",
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("expected %v, got %v", want, lines)
+	}
+
+	lines = shellUnsets(baseDir, []string{"go", "node"}, environ, config{}, "fish")
+	want = []string{
+		"set -gx PATH '/usr/bin'",
+		"set -e LAV_SHELL",
+		"set -e LAV_GO_VERSION",
+		"set -e LAV_NODE_VERSION",
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("expected %v, got %v", want, lines)
+	}
+}
+
+func TestResolveActiveVersion_ShellSession(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.4", "go")
+
+	t.Setenv("LAV_GO_VERSION", "1.22.0")
+	t.Setenv("LAV_SHELL", "go@1.22.0")
+	version, source, err := resolveActiveVersion(baseDir, "go", t.TempDir())
+	if err != nil || version != "1.22.0" || source != "lav shell" {
+		t.Errorf("unexpected result: %s %s %v", version, source, err)
+	}
+
+	t.Setenv("LAV_SHELL", "")
+	if _, source, _ := resolveActiveVersion(baseDir, "go", t.TempDir()); source != "LAV_GO_VERSION" {
+		t.Errorf("expected LAV_GO_VERSION as source, got %s", source)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Link modes of an app, set with mode in its [apps.<name>] config table. In
+// symlink mode, the default, bin links point at the global current version.
+// In shim mode they point at the lav executable, which picks the version
+// each time the executable runs.
+const (
+	modeSymlink = "symlink"
+	modeShim    = "shim"
+)
+
+// appLinkTarget returns the target of the bin link for executable name of
+// appName: lav itself in shim mode, its current version otherwise.
+func appLinkTarget(binDir, baseDir, appName, name string) (string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if cfg.Apps[appName].Mode == modeShim {
+		return shimTarget()
+	}
+	return binLinkTarget(binDir, baseDir, appName, name)
+}
+
+// shimTarget returns the path shims link to. The lav found on PATH is
+// preferred over the running executable's resolved path, since it stays
+// valid when lav itself is installed with lav and upgraded.
+func shimTarget() (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+
+	if onPath, err := exec.LookPath("lav"); err == nil {
+		a, errA := os.Stat(onPath)
+		b, errB := os.Stat(self)
+		if errA == nil && errB == nil && os.SameFile(a, b) {
+			return filepath.Abs(onPath)
+		}
+	}
+	return self, nil
+}
+
+// isShimLink reports whether link points at the lav executable.
+func isShimLink(link string) bool {
+	target, err := shimTarget()
+	if err != nil {
+		return false
+	}
+	a, errA := os.Stat(link)
+	b, errB := os.Stat(target)
+	return errA == nil && errB == nil && os.SameFile(a, b)
+}
+
+// ownsLink reports whether link is a link of appName that lav may remove or
+// replace on its behalf: owned by appName and pointing into its directory
+// or, in shim mode, at lav.
+func ownsLink(baseDir string, reg *linkRegistry, link, appName string) bool {
+	info, err := os.Lstat(link)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 || reg.owner(baseDir, link) != appName {
+		return false
+	}
+	return linkPointsInto(link, filepath.Join(baseDir, appName)) || isShimLink(link)
+}
+
+// shimApp returns the app whose shim is named name, or "" if name is not a
+// shim in any bin directory.
+func shimApp(baseDir, name string) string {
+	if validateName("executable", name) != nil {
+		return ""
+	}
+	binDirs, err := absBinDirs()
+	if err != nil {
+		return ""
+	}
+	reg, err := loadLinkRegistry(baseDir)
+	if err != nil {
+		return ""
+	}
+
+	for _, binDir := range binDirs {
+		link := filepath.Join(binDir, name)
+		if app := reg.Links[link]; app != "" && isShimLink(link) {
+			return app
+		}
+	}
+	return ""
+}
+
+// runShim replaces the lav process with executable name of app, in the
+// version resolved for the working directory.
+func runShim(baseDir, app, name string, args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	version, _, err := resolveActiveVersion(baseDir, app, cwd)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(baseDir, app, version, "bin", name)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s %s has no executable %s", app, version, name)
+	}
+	return execProcess(path, append([]string{name}, args...), os.Environ())
+}
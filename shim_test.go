@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShimMode(t *testing.T) {
+	home := setupTestEnv(t)
+	configPath := filepath.Join(home, "config.toml")
+	os.WriteFile(configPath, []byte("[apps.go]\nmode = \"shim\"\n"), 0644)
+
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	binDir := filepath.Join(home, ".local", "bin")
+	installTestVersion(t, baseDir, "go", "1.23.0", "go")
+	installTestVersion(t, baseDir, "node", "22.0.0", "node")
+
+	// Shim-mode apps link to lav, others to their current version
+	if !isShimLink(filepath.Join(binDir, "go")) {
+		t.Error("go should be linked to lav")
+	}
+	if isShimLink(filepath.Join(binDir, "node")) {
+		t.Error("node should be a plain symlink")
+	}
+	if app := shimApp(baseDir, "go"); app != "go" {
+		t.Errorf("expected go shim, got %q", app)
+	}
+	if app := shimApp(baseDir, "node"); app != "" {
+		t.Errorf("node is not a shim, got %q", app)
+	}
+
+	// The current symlink is still maintained
+	if current, _ := getCurrentVersion(baseDir, "go"); current != "1.23.0" {
+		t.Errorf("expected current 1.23.0, got %s", current)
+	}
+
+	// Switching back to symlink mode rewrites the link
+	os.WriteFile(configPath, []byte("[apps.go]\nmode = \"symlink\"\n"), 0644)
+	if _, err := relinkApps(baseDir, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(binDir, "go")); string(data) != "go 1.23.0" {
+		t.Errorf("expected a symlink to go 1.23.0, got %q", data)
+	}
+}
+
+func TestShimMode_Remove(t *testing.T) {
+	home := setupTestEnv(t)
+	configPath := filepath.Join(home, "config.toml")
+	os.WriteFile(configPath, []byte("[apps.go]\nmode = \"shim\"\n"), 0644)
+
+	baseDir := filepath.Join(home, ".local", "share", "lav")
+	installTestVersion(t, baseDir, "go", "1.23.0", "go")
+
+	removed, err := removeApp(baseDir, "go", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(removed) != 1 {
+		t.Errorf("expected the shim to be removed, got %v", removed)
+	}
+}
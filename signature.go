@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Minisign signature algorithms: "Ed" signs the file contents directly,
+// "ED" signs the BLAKE2b-512 hash of the file (the default since minisign
+// 0.8).
+const (
+	minisignLegacy    = "Ed"
+	minisignPrehashed = "ED"
+)
+
+// publicKey is a minisign ed25519 public key.
+type publicKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+// keyID returns the key ID as minisign prints it: uppercase hex of the
+// little-endian 64-bit ID.
+func (k publicKey) keyID() string {
+	id := k.id
+	for i, j := 0, len(id)-1; i < j; i, j = i+1, j-1 {
+		id[i], id[j] = id[j], id[i]
+	}
+	return strings.ToUpper(hex.EncodeToString(id[:]))
+}
+
+// encode returns the base64 form of the key as used in minisign .pub files.
+func (k publicKey) encode() string {
+	data := append([]byte(minisignLegacy), k.id[:]...)
+	return base64.StdEncoding.EncodeToString(append(data, k.key...))
+}
+
+// signature is a parsed minisign .minisig file.
+type signature struct {
+	algorithm      string
+	keyID          [8]byte
+	sig            []byte
+	trustedComment string
+	globalSig      []byte
+}
+
+// parsePublicKey parses a minisign public key, either the bare base64 line
+// or the contents of a .pub file including its comment line.
+func parsePublicKey(s string) (publicKey, error) {
+	var pk publicKey
+
+	var encoded string
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			encoded = line
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) != 2+8+ed25519.PublicKeySize {
+		return pk, fmt.Errorf("invalid public key")
+	}
+	if string(data[:2]) != minisignLegacy {
+		return pk, fmt.Errorf("unsupported public key algorithm: %q", data[:2])
+	}
+
+	copy(pk.id[:], data[2:10])
+	pk.key = ed25519.PublicKey(data[10:])
+	return pk, nil
+}
+
+func parseSignature(data []byte) (signature, error) {
+	var sig signature
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		return sig, fmt.Errorf("invalid signature file")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return sig, fmt.Errorf("invalid signature")
+	}
+	sig.algorithm = string(raw[:2])
+	if sig.algorithm != minisignLegacy && sig.algorithm != minisignPrehashed {
+		return sig, fmt.Errorf("unsupported signature algorithm: %q", sig.algorithm)
+	}
+	copy(sig.keyID[:], raw[2:10])
+	sig.sig = raw[10:]
+
+	comment, ok := strings.CutPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	if !ok {
+		return sig, fmt.Errorf("invalid signature file: missing trusted comment")
+	}
+	sig.trustedComment = comment
+
+	sig.globalSig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(sig.globalSig) != ed25519.SignatureSize {
+		return sig, fmt.Errorf("invalid global signature")
+	}
+
+	return sig, nil
+}
+
+// verifySignatureFile checks that sig is a valid signature of the file at
+// path made by one of keys.
+func verifySignatureFile(path string, sig signature, keys []publicKey) error {
+	var key *publicKey
+	for i := range keys {
+		if keys[i].id == sig.keyID {
+			key = &keys[i]
+			break
+		}
+	}
+	if key == nil {
+		return fmt.Errorf("signature was made by an untrusted key")
+	}
+
+	message, err := signedMessage(path, sig.algorithm)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(key.key, message, sig.sig) {
+		return fmt.Errorf("invalid signature for %s", filepath.Base(path))
+	}
+
+	// The global signature covers the trusted comment
+	global := append(append([]byte{}, sig.sig...), sig.trustedComment...)
+	if !ed25519.Verify(key.key, global, sig.globalSig) {
+		return fmt.Errorf("invalid trusted comment signature for %s", filepath.Base(path))
+	}
+
+	return nil
+}
+
+// maxLegacySignedSize is the largest file whose legacy signature is
+// checked. Legacy signatures cover the file contents, which must be held in
+// memory to verify them.
+const maxLegacySignedSize = 64 << 20
+
+// signedMessage returns the message signed for the file at path: its
+// BLAKE2b-512 hash, computed while streaming the file, for pre-hashed
+// signatures and its contents for legacy ones.
+func signedMessage(path, algorithm string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if algorithm == minisignPrehashed {
+		h, err := blake2b.New512(nil)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(h, f); err != nil {
+			return nil, err
+		}
+		return h.Sum(nil), nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, maxLegacySignedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxLegacySignedSize {
+		return nil, fmt.Errorf("%s is too large for a legacy signature; sign it with minisign 0.8 or later", filepath.Base(path))
+	}
+	return data, nil
+}
+
+// verifyArtifactSignature enforces the signature policy for appName: when
+// the app has trusted keys, the artifact at path must carry a valid
+// signature from one of them. sigSrc is the path or URL of the .minisig
+// file.
+func verifyArtifactSignature(baseDir, appName, path, sigSrc string, explicit bool) error {
+	keys, err := loadTrustedKeys(baseDir, appName)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		if explicit {
+			return fmt.Errorf("no trusted keys for %s; add one with 'lav trust add %s <pubkey>'", appName, appName)
+		}
+		return nil
+	}
+
+	data, err := readFileOrURL(sigSrc)
+	if err != nil {
+		return fmt.Errorf("%s requires a signed artifact: failed to read signature %s: %w", appName, sigSrc, err)
+	}
+
+	sig, err := parseSignature(data)
+	if err != nil {
+		return err
+	}
+
+	return verifySignatureFile(path, sig, keys)
+}
+
+// trustDir returns the directory holding the trusted public keys of appName,
+// one <keyid>.pub file per key.
+func trustDir(baseDir, appName string) string {
+	return filepath.Join(baseDir, ".trust", appName)
+}
+
+func loadTrustedKeys(baseDir, appName string) ([]publicKey, error) {
+	if err := validateName("app", appName); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(trustDir(baseDir, appName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var keys []publicKey
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".pub") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(trustDir(baseDir, appName), entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		key, err := parsePublicKey(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].keyID() < keys[j].keyID() })
+	return keys, nil
+}
+
+// addTrustedKey adds a public key for appName. pubkey is either the base64
+// key itself or a path to a minisign .pub file.
+func addTrustedKey(baseDir, appName, pubkey string) (publicKey, error) {
+	if err := validateName("app", appName); err != nil {
+		return publicKey{}, err
+	}
+	if data, err := os.ReadFile(pubkey); err == nil {
+		pubkey = string(data)
+	}
+
+	key, err := parsePublicKey(pubkey)
+	if err != nil {
+		return key, err
+	}
+
+	dir := trustDir(baseDir, appName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return key, fmt.Errorf("failed to create trust directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "untrusted comment: minisign public key %s\n", key.keyID())
+	fmt.Fprintln(&buf, key.encode())
+	if err := os.WriteFile(filepath.Join(dir, key.keyID()+".pub"), buf.Bytes(), 0644); err != nil {
+		return key, fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	return key, nil
+}
+
+func removeTrustedKey(baseDir, appName, keyID string) error {
+	if err := validateName("app", appName); err != nil {
+		return err
+	}
+	if err := validateName("key ID", keyID); err != nil {
+		return err
+	}
+	path := filepath.Join(trustDir(baseDir, appName), strings.ToUpper(keyID)+".pub")
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("key %s is not trusted for %s", keyID, appName)
+		}
+		return err
+	}
+
+	// Drop the app's trust directory once its last key is gone
+	os.Remove(trustDir(baseDir, appName))
+	return nil
+}
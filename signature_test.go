@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+type testSigner struct {
+	pub  publicKey
+	priv ed25519.PrivateKey
+}
+
+func newTestSigner(t *testing.T) testSigner {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s testSigner
+	rand.Read(s.pub.id[:])
+	s.pub.key = pub
+	s.priv = priv
+	return s
+}
+
+// sign returns a minisign signature file for data.
+func (s testSigner) sign(data []byte, algorithm string) []byte {
+	message := data
+	if algorithm == minisignPrehashed {
+		sum := blake2b.Sum512(data)
+		message = sum[:]
+	}
+	sig := ed25519.Sign(s.priv, message)
+	comment := "timestamp:1700000000"
+	global := ed25519.Sign(s.priv, append(append([]byte{}, sig...), comment...))
+
+	raw := append([]byte(algorithm), s.pub.id[:]...)
+	raw = append(raw, sig...)
+	return []byte(fmt.Sprintf("untrusted comment: signature\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(raw), comment, base64.StdEncoding.EncodeToString(global)))
+}
+
+func TestParsePublicKey(t *testing.T) {
+	signer := newTestSigner(t)
+	encoded := signer.pub.encode()
+
+	key, err := parsePublicKey("untrusted comment: minisign public key\n" + encoded + "\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.keyID() != signer.pub.keyID() || !key.key.Equal(signer.pub.key) {
+		t.Error("parsed key does not match")
+	}
+
+	if _, err := parsePublicKey("not a key"); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
+
+func TestVerifySignatureFile(t *testing.T) {
+	signer := newTestSigner(t)
+	other := newTestSigner(t)
+	path := filepath.Join(t.TempDir(), "app.tar.gz")
+	os.WriteFile(path, []byte("artifact"), 0644)
+
+	for _, algorithm := range []string{minisignLegacy, minisignPrehashed} {
+		sig, err := parseSignature(signer.sign([]byte("artifact"), algorithm))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", algorithm, err)
+		}
+		if err := verifySignatureFile(path, sig, []publicKey{other.pub, signer.pub}); err != nil {
+			t.Errorf("%s: expected valid signature, got %v", algorithm, err)
+		}
+		if err := verifySignatureFile(path, sig, []publicKey{other.pub}); err == nil {
+			t.Errorf("%s: expected error for untrusted key", algorithm)
+		}
+	}
+
+	// Signature over different content is rejected
+	sig, _ := parseSignature(signer.sign([]byte("tampered"), minisignPrehashed))
+	if err := verifySignatureFile(path, sig, []publicKey{signer.pub}); err == nil {
+		t.Error("expected error for bad signature")
+	}
+
+	// Legacy signatures need the whole file in memory, so their size is capped
+	large := filepath.Join(t.TempDir(), "large.tar.gz")
+	os.WriteFile(large, nil, 0644)
+	os.Truncate(large, maxLegacySignedSize+1)
+	sig, _ = parseSignature(signer.sign([]byte("artifact"), minisignLegacy))
+	if err := verifySignatureFile(large, sig, []publicKey{signer.pub}); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("expected error for a large file, got %v", err)
+	}
+}
+
+func TestTrustedKeys(t *testing.T) {
+	baseDir := t.TempDir()
+	signer := newTestSigner(t)
+
+	// Keys can be added from a .pub file
+	pubFile := filepath.Join(t.TempDir(), "minisign.pub")
+	os.WriteFile(pubFile, []byte("untrusted comment: test\n"+signer.pub.encode()+"\n"), 0644)
+	if _, err := addTrustedKey(baseDir, "app", pubFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	keys, err := loadTrustedKeys(baseDir, "app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 1 || keys[0].keyID() != signer.pub.keyID() {
+		t.Errorf("expected trusted key %s, got %v", signer.pub.keyID(), keys)
+	}
+
+	if err := removeTrustedKey(baseDir, "app", signer.pub.keyID()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys, _ := loadTrustedKeys(baseDir, "app"); len(keys) != 0 {
+		t.Errorf("expected no keys after removal, got %d", len(keys))
+	}
+
+	// The trust store is not listed as an app
+	if apps, _ := listApps(baseDir); len(apps) != 0 {
+		t.Errorf("expected no apps, got %v", apps)
+	}
+}
+
+func TestTrustedKeys_InvalidNames(t *testing.T) {
+	baseDir := t.TempDir()
+	signer := newTestSigner(t)
+
+	if _, err := addTrustedKey(baseDir, "../x", signer.pub.encode()); err == nil {
+		t.Error("expected error for an app name outside the trust store")
+	}
+	if err := removeTrustedKey(baseDir, "../x", signer.pub.keyID()); err == nil {
+		t.Error("expected error for an app name outside the trust store")
+	}
+	if err := removeTrustedKey(baseDir, "app", "../../victim"); err == nil || !strings.Contains(err.Error(), "invalid key ID") {
+		t.Errorf("expected error for a key ID outside the trust store, got %v", err)
+	}
+}
+
+func TestInstallPath_Signature(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	srcDir := t.TempDir()
+	signer := newTestSigner(t)
+
+	binary := filepath.Join(srcDir, "tool")
+	os.WriteFile(binary, []byte("tool contents"), 0755)
+
+	// Apps without trusted keys install unsigned artifacts
+	if err := installPath(baseDir, binary, "tool", "1.0.0", installOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := addTrustedKey(baseDir, "tool", signer.pub.encode()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unsigned artifacts are refused once the app has trusted keys
+	if err := installPath(baseDir, binary, "tool", "2.0.0", installOptions{}); err == nil {
+		t.Error("expected error for unsigned artifact")
+	}
+
+	// A badly signed artifact is refused
+	os.WriteFile(binary+".minisig", signer.sign([]byte("other contents"), minisignPrehashed), 0644)
+	if err := installPath(baseDir, binary, "tool", "2.0.0", installOptions{}); err == nil {
+		t.Error("expected error for bad signature")
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "tool", "2.0.0")); !os.IsNotExist(err) {
+		t.Error("version directory should not exist after a failed verification")
+	}
+
+	// A valid <path>.minisig is picked up automatically
+	os.WriteFile(binary+".minisig", signer.sign([]byte("tool contents"), minisignPrehashed), 0644)
+	if err := installPath(baseDir, binary, "tool", "2.0.0", installOptions{}); err != nil {
+		t.Errorf("expected signed install to succeed, got %v", err)
+	}
+
+	// Folders cannot be verified
+	folder := filepath.Join(srcDir, "folder")
+	os.MkdirAll(filepath.Join(folder, "bin"), 0755)
+	if err := installPath(baseDir, folder, "tool", "3.0.0", installOptions{}); err == nil {
+		t.Error("expected error for folder install of an app with trusted keys")
+	}
+}
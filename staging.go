@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// stagingDir returns the directory where installs are assembled before they
+// are renamed into place. It lives inside the base dir so the final rename
+// stays on one filesystem.
+func stagingDir(baseDir string) string {
+	return filepath.Join(baseDir, ".staging")
+}
+
+// newStagingDir creates an empty staging directory for an install. The name
+// starts with the installing process ID so that cleanStaleStaging can tell
+// abandoned directories from ones still in use.
+func newStagingDir(baseDir, appName, version string) (string, error) {
+	dir := stagingDir(baseDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	staged, err := os.MkdirTemp(dir, fmt.Sprintf("%d-%s-%s-", os.Getpid(), appName, version))
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	// MkdirTemp creates the directory 0700; it becomes the version directory
+	if err := os.Chmod(staged, 0755); err != nil {
+		os.RemoveAll(staged)
+		return "", err
+	}
+	return staged, nil
+}
+
+// staleStagingDirs returns the staging directories left behind by installs
+// whose process is no longer running.
+func staleStagingDirs(baseDir string) ([]string, error) {
+	entries, err := os.ReadDir(stagingDir(baseDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var stale []string
+	for _, entry := range entries {
+		pidStr, _, _ := strings.Cut(entry.Name(), "-")
+		pid, err := strconv.Atoi(pidStr)
+		if err == nil && processAlive(pid) {
+			continue
+		}
+		stale = append(stale, filepath.Join(stagingDir(baseDir), entry.Name()))
+	}
+	return stale, nil
+}
+
+// cleanStaleStaging removes staging directories left behind by installs
+// whose process is no longer running.
+func cleanStaleStaging(baseDir string) error {
+	stale, err := staleStagingDirs(baseDir)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range stale {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commitVersion moves the fully populated directory staged into place as
+// <app>/<version>. An existing version is swapped out atomically and
+// removed, rather than having the new files merged onto it.
+func commitVersion(baseDir, staged, appName, version string) error {
+	appDir := filepath.Join(baseDir, appName)
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		return fmt.Errorf("failed to create app directory: %w", err)
+	}
+
+	versionDir := filepath.Join(appDir, version)
+	if _, err := os.Lstat(versionDir); os.IsNotExist(err) {
+		if err := os.Rename(staged, versionDir); err != nil {
+			return fmt.Errorf("failed to move version into place: %w", err)
+		}
+		return nil
+	}
+
+	if err := exchangePaths(staged, versionDir); err != nil {
+		return fmt.Errorf("failed to replace existing version: %w", err)
+	}
+
+	// staged now holds the previous contents of the version
+	if err := os.RemoveAll(staged); err != nil {
+		return fmt.Errorf("failed to remove previous version: %w", err)
+	}
+
+	return nil
+}
+
+// exchangeByRename swaps a and b with two renames. It is the fallback for
+// platforms and filesystems without an atomic exchange; b is briefly
+// missing between the renames.
+func exchangeByRename(a, b string) error {
+	tmp := a + ".old"
+	if err := os.Rename(b, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(a, b); err != nil {
+		os.Rename(tmp, b)
+		return err
+	}
+	return os.Rename(tmp, a)
+}
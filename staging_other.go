@@ -0,0 +1,17 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// processAlive reports whether a process with the given ID exists. Where
+// processes cannot be signalled, finding the process fails once it has
+// exited.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallDirectory_ReplacesExistingVersion(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+
+	first := t.TempDir()
+	os.MkdirAll(filepath.Join(first, "bin"), 0755)
+	os.WriteFile(filepath.Join(first, "bin", "app"), []byte("v1"), 0755)
+	os.WriteFile(filepath.Join(first, "obsolete.txt"), []byte("old"), 0644)
+
+	second := t.TempDir()
+	os.MkdirAll(filepath.Join(second, "bin"), 0755)
+	os.WriteFile(filepath.Join(second, "bin", "app"), []byte("v2"), 0755)
+
+	if err := installDirectory(baseDir, first, "app", "1.0.0", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := installDirectory(baseDir, second, "app", "1.0.0", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	versionDir := filepath.Join(baseDir, "app", "1.0.0")
+	if data, _ := os.ReadFile(filepath.Join(versionDir, "bin", "app")); string(data) != "v2" {
+		t.Errorf("expected new binary, got %q", data)
+	}
+	// Files are not merged onto the old tree
+	if _, err := os.Stat(filepath.Join(versionDir, "obsolete.txt")); !os.IsNotExist(err) {
+		t.Error("files from the previous install should be gone")
+	}
+
+	if info, _ := os.Stat(versionDir); info.Mode().Perm() != 0755 {
+		t.Errorf("expected version directory mode 0755, got %v", info.Mode().Perm())
+	}
+
+	// Nothing is left in staging
+	entries, _ := os.ReadDir(stagingDir(baseDir))
+	if len(entries) != 0 {
+		t.Errorf("expected empty staging directory, got %d entries", len(entries))
+	}
+}
+
+func TestInstallDirectory_FailedCopyLeavesNoVersion(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	os.WriteFile(filepath.Join(src, "bin", "app"), []byte("v1"), 0755)
+	// A dangling symlink makes the copy fail halfway through
+	os.Symlink("missing", filepath.Join(src, "zz-broken"))
+
+	if err := installDirectory(baseDir, src, "app", "1.0.0", false); err == nil {
+		t.Fatal("expected copy error")
+	}
+
+	if _, err := os.Stat(filepath.Join(baseDir, "app", "1.0.0")); !os.IsNotExist(err) {
+		t.Error("a failed install should not leave a version directory")
+	}
+	if versions, _ := listVersions(baseDir, "app"); len(versions) != 0 {
+		t.Errorf("expected no versions, got %v", versions)
+	}
+}
+
+func TestCleanStaleStaging(t *testing.T) {
+	baseDir := t.TempDir()
+
+	stale := filepath.Join(stagingDir(baseDir), "999999999-app-1.0.0-123")
+	os.MkdirAll(stale, 0755)
+	unknown := filepath.Join(stagingDir(baseDir), "leftover")
+	os.MkdirAll(unknown, 0755)
+
+	active, err := newStagingDir(baseDir, "app", "2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cleanStaleStaging(baseDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale staging directory should be removed")
+	}
+	if _, err := os.Stat(unknown); !os.IsNotExist(err) {
+		t.Error("unrecognised staging directory should be removed")
+	}
+	if _, err := os.Stat(active); err != nil {
+		t.Error("staging directory of a running install should be kept")
+	}
+}
+
+func TestExchangePaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a")
+	b := filepath.Join(tmpDir, "b")
+	os.MkdirAll(a, 0755)
+	os.MkdirAll(b, 0755)
+	os.WriteFile(filepath.Join(a, "name"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(b, "name"), []byte("b"), 0644)
+
+	if err := exchangePaths(a, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(a, "name")); string(data) != "b" {
+		t.Errorf("expected a to hold b's contents, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(b, "name")); string(data) != "a" {
+		t.Errorf("expected b to hold a's contents, got %q", data)
+	}
+}
+
+func TestInstallPath_InvalidNames(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	os.WriteFile(filepath.Join(src, "bin", "tool"), []byte("v1"), 0755)
+
+	if err := installPath(baseDir, src, "tool", "1.0.0", installOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// "current" would be swapped with the current symlink
+	if err := installPath(baseDir, src, "tool", "current", installOptions{}); err == nil {
+		t.Error("expected an error for version current")
+	}
+	if current, err := getCurrentVersion(baseDir, "tool"); err != nil || current != "1.0.0" {
+		t.Errorf("expected current=1.0.0, got %q (%v)", current, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(baseDir, "tool", "1.0.0", "bin", "tool")); string(data) != "v1" {
+		t.Errorf("existing version was changed: %q", data)
+	}
+
+	for _, names := range [][2]string{{"../../escape", "1.0.0"}, {"tool", "../escape"}, {".hidden", "1.0.0"}, {"tool", ""}} {
+		if err := installPath(baseDir, src, names[0], names[1], installOptions{}); err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("expected an invalid name error for %v, got %v", names, err)
+		}
+	}
+}
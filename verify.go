@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// manifestFileName is the file in each version directory that lists the
+// files installed with the version.
+const manifestFileName = ".lav-manifest.json"
+
+// isLavFile reports whether name, a file at the root of a version
+// directory, is one lav keeps there rather than part of the version.
+func isLavFile(name string) bool {
+	return name == metaFileName || name == manifestFileName
+}
+
+// manifestEntry describes an installed file. Directories are not listed.
+type manifestEntry struct {
+	Path   string `json:"path"` // slash-separated, relative to the version directory
+	Mode   string `json:"mode"` // e.g. -rwxr-xr-x
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"` // of regular files
+	Target string `json:"target,omitempty"` // of symlinks
+}
+
+// manifest lists the files of a version, sorted by path.
+type manifest struct {
+	Files []manifestEntry `json:"files"`
+}
+
+func manifestPath(baseDir, app, version string) string {
+	return filepath.Join(baseDir, app, version, manifestFileName)
+}
+
+// buildManifest returns the manifest of the version directory dir.
+func buildManifest(dir string) (manifest, error) {
+	m := manifest{Files: []manifestEntry{}}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Dir(path) == dir && isLavFile(d.Name()) {
+			return nil
+		}
+		entry, err := describeFile(dir, path)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, entry)
+		return nil
+	})
+	return m, err
+}
+
+// describeFile returns the manifest entry of path, a file in the version
+// directory dir.
+func describeFile(dir, path string) (manifestEntry, error) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+
+	entry := manifestEntry{Path: filepath.ToSlash(rel), Mode: info.Mode().String()}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		if entry.Target, err = os.Readlink(path); err != nil {
+			return manifestEntry{}, err
+		}
+	case info.Mode().IsRegular():
+		entry.Size = info.Size()
+		if entry.SHA256, err = fileSHA256(path); err != nil {
+			return manifestEntry{}, err
+		}
+	}
+	return entry, nil
+}
+
+// readManifest reads the manifest of version. Versions installed before lav
+// recorded manifests have none; nil is returned for them.
+func readManifest(baseDir, app, version string) (*manifest, error) {
+	data, err := os.ReadFile(manifestPath(baseDir, app, version))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", manifestPath(baseDir, app, version), err)
+	}
+	return &m, nil
+}
+
+// writeManifest replaces the manifest of version.
+func writeManifest(baseDir, app, version string, m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := manifestPath(baseDir, app, version)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// verifyIssue is a difference between a version directory and its
+// manifest. Kind is "modified" (content, type or symlink target changed),
+// "mode" (permissions changed), "missing" or "extra".
+type verifyIssue struct {
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+}
+
+// verifyVersion compares version with its manifest and returns the
+// differences, sorted by path. It returns a nil manifest, and no issues,
+// for versions without one.
+func verifyVersion(baseDir, app, version string) (*manifest, []verifyIssue, error) {
+	recorded, err := readManifest(baseDir, app, version)
+	if err != nil || recorded == nil {
+		return nil, nil, err
+	}
+
+	dir := filepath.Join(baseDir, app, version)
+	issues := []verifyIssue{}
+	seen := make(map[string]bool)
+	for _, want := range recorded.Files {
+		seen[want.Path] = true
+		path := filepath.Join(dir, filepath.FromSlash(want.Path))
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			issues = append(issues, verifyIssue{Kind: "missing", Path: want.Path})
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		got := manifestEntry{Path: want.Path, Mode: info.Mode().String()}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			if got.Target, err = os.Readlink(path); err != nil {
+				return nil, nil, err
+			}
+		case info.Mode().IsRegular():
+			got.Size = info.Size()
+			// Files whose size changed are modified; skip hashing them
+			if got.Size == want.Size {
+				if got.SHA256, err = fileSHA256(path); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+
+		switch {
+		case got.Mode[0] != want.Mode[0] || got.Size != want.Size || got.SHA256 != want.SHA256 || got.Target != want.Target:
+			issues = append(issues, verifyIssue{Kind: "modified", Path: want.Path})
+		case got.Mode != want.Mode && info.Mode()&os.ModeSymlink == 0:
+			issues = append(issues, verifyIssue{Kind: "mode", Path: want.Path})
+		}
+	}
+
+	current, err := buildManifestPaths(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, path := range current {
+		if !seen[path] {
+			issues = append(issues, verifyIssue{Kind: "extra", Path: path})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Path < issues[j].Path })
+	return recorded, issues, nil
+}
+
+// buildManifestPaths returns the paths buildManifest would list for dir,
+// without hashing the files.
+func buildManifestPaths(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Dir(path) == dir && isLavFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	return paths, err
+}
+
+// repairSource returns the artifact or folder version was installed from,
+// as long as it still holds what was installed: the cached download for
+// URLs, or the original path for local installs.
+func repairSource(baseDir string, meta *versionMeta) (string, error) {
+	if meta == nil {
+		return "", fmt.Errorf("no install metadata to find the source")
+	}
+
+	if isURL(meta.Source) {
+		name, err := artifactName(meta.Source)
+		if err != nil {
+			return "", err
+		}
+		cached := cachedArtifactPath(baseDir, meta.SHA256, name)
+		if sum, err := fileSHA256(cached); err != nil || sum != meta.SHA256 {
+			return "", fmt.Errorf("%s is not in the download cache; reinstall it with lav install", meta.Source)
+		}
+		return cached, nil
+	}
+
+	info, err := os.Stat(meta.Source)
+	if err != nil {
+		return "", fmt.Errorf("source %s is no longer available", meta.Source)
+	}
+	if info.IsDir() != (meta.SHA256 == "") {
+		return "", fmt.Errorf("source %s has changed since it was installed", meta.Source)
+	}
+	if !info.IsDir() {
+		if sum, err := fileSHA256(meta.Source); err != nil || sum != meta.SHA256 {
+			return "", fmt.Errorf("source %s has changed since it was installed", meta.Source)
+		}
+	}
+	return meta.Source, nil
+}
+
+// repairVersion restores version from the source it was installed from.
+// The source is staged as for an install and must produce the files in the
+// recorded manifest; the version is then swapped in atomically, keeping its
+// install metadata. Links and the current version are left as they are.
+func repairVersion(baseDir, app, version string) error {
+	recorded, err := readManifest(baseDir, app, version)
+	if err != nil {
+		return err
+	}
+	if recorded == nil {
+		return fmt.Errorf("%s %s has no manifest", app, version)
+	}
+	meta, err := readVersionMeta(baseDir, app, version)
+	if err != nil {
+		return err
+	}
+	src, err := repairSource(baseDir, meta)
+	if err != nil {
+		return err
+	}
+
+	staged, err := newStagingDir(baseDir, app, version)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staged)
+
+	root := staged
+	switch {
+	case meta.SHA256 == "":
+		err = copyDir(src, staged)
+	case isArchive(src):
+		root, err = stageArchive(src, staged)
+	default:
+		err = stageBinary(src, staged)
+	}
+	if err != nil {
+		return err
+	}
+
+	restored, err := buildManifest(root)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(restored.Files, recorded.Files) {
+		return fmt.Errorf("source %s does not match the manifest of %s %s", meta.Source, app, version)
+	}
+
+	dir := filepath.Join(baseDir, app, version)
+	for _, name := range []string{metaFileName, manifestFileName} {
+		if err := copyFile(filepath.Join(dir, name), filepath.Join(root, name)); err != nil {
+			return err
+		}
+	}
+	return commitVersion(baseDir, root, app, version)
+}
+
+// verifyTargets returns the versions lav verify checks for args: every
+// installed version, those of an app, or a single version of it.
+func verifyTargets(baseDir string, args []string) ([]verifiedJSON, error) {
+	apps := args[:min(len(args), 1)]
+	if len(args) == 0 {
+		var err error
+		if apps, err = listApps(baseDir); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	var targets []verifiedJSON
+	for _, app := range apps {
+		versions, err := listVersions(baseDir, app)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("%s is not installed", app)
+			}
+			return nil, err
+		}
+		if len(args) == 2 {
+			version, err := resolveVersion(app, versions, args[1], true)
+			if err != nil {
+				return nil, err
+			}
+			versions = []string{version}
+		}
+		for _, version := range versions {
+			targets = append(targets, verifiedJSON{App: app, Version: version})
+		}
+	}
+	return targets, nil
+}
+
+// verifyTarget verifies version of app and, with repair, restores it if it
+// is damaged.
+func verifyTarget(baseDir, app, version string, repair bool) (verifiedJSON, error) {
+	result := verifiedJSON{App: app, Version: version, Status: "ok", Issues: []verifyIssue{}}
+	recorded, issues, err := verifyVersion(baseDir, app, version)
+	if err != nil {
+		return verifiedJSON{}, err
+	}
+	if recorded == nil {
+		result.Status = "unverified"
+		return result, nil
+	}
+	if len(issues) == 0 {
+		return result, nil
+	}
+
+	result.Status = "failed"
+	result.Issues = issues
+	if repair {
+		if err := repairVersion(baseDir, app, version); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Status = "repaired"
+		}
+	}
+	return result, nil
+}
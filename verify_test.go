@@ -0,0 +1,180 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestInstallPath_RecordsManifest(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "go.tar.gz")
+	writeTestTarGz(t, archive, []testArchiveEntry{
+		{name: "go/bin/go", body: "#!/bin/sh\n", mode: 0755},
+		{name: "go/VERSION", body: "go1.25.6\n", mode: 0644},
+		{name: "go/misc/version", linkname: "../VERSION"},
+	})
+
+	if err := installPath(baseDir, archive, "go", "1.25.6", installOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m, err := readManifest(baseDir, "go", "1.25.6")
+	if err != nil || m == nil {
+		t.Fatalf("expected manifest, got %v %v", m, err)
+	}
+	want := []manifestEntry{
+		{Path: "VERSION", Mode: "-rw-r--r--", Size: 9, SHA256: sha256Hex([]byte("go1.25.6\n"))},
+		{Path: "bin/go", Mode: "-rwxr-xr-x", Size: 10, SHA256: sha256Hex([]byte("#!/bin/sh\n"))},
+		{Path: "misc/version", Mode: "Lrwxrwxrwx", Target: "../VERSION"},
+	}
+	if !slices.Equal(m.Files, want) {
+		t.Errorf("expected %+v, got %+v", want, m.Files)
+	}
+
+	// The files lav keeps in the version directory are not counted
+	files, _, _ := versionStats(filepath.Join(baseDir, "go", "1.25.6"))
+	if files != 2 {
+		t.Errorf("expected 2 files, got %d", files)
+	}
+}
+
+func TestVerifyVersion(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	os.WriteFile(filepath.Join(src, "bin", "tool"), []byte("tool"), 0755)
+	os.WriteFile(filepath.Join(src, "bin", "helper"), []byte("helper"), 0755)
+	os.WriteFile(filepath.Join(src, "README"), []byte("readme"), 0644)
+	os.WriteFile(filepath.Join(src, "LICENSE"), []byte("license"), 0644)
+
+	if err := installPath(baseDir, src, "tool", "1.0.0", installOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, issues, err := verifyVersion(baseDir, "tool", "1.0.0"); err != nil || len(issues) != 0 {
+		t.Fatalf("expected a clean version, got %v %v", issues, err)
+	}
+
+	dir := filepath.Join(baseDir, "tool", "1.0.0")
+	os.WriteFile(filepath.Join(dir, "bin", "tool"), []byte("evil"), 0755)
+	os.Chmod(filepath.Join(dir, "bin", "helper"), 0700)
+	os.Remove(filepath.Join(dir, "README"))
+	os.WriteFile(filepath.Join(dir, "bin", "extra"), []byte("extra"), 0755)
+
+	_, issues, err := verifyVersion(baseDir, "tool", "1.0.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []verifyIssue{
+		{Kind: "missing", Path: "README"},
+		{Kind: "extra", Path: "bin/extra"},
+		{Kind: "mode", Path: "bin/helper"},
+		{Kind: "modified", Path: "bin/tool"},
+	}
+	if !slices.Equal(issues, want) {
+		t.Errorf("expected %v, got %v", want, issues)
+	}
+}
+
+func TestVerifyVersion_NoManifest(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+
+	result, err := verifyTarget(baseDir, "go", "1.22.0", false)
+	if err != nil || result.Status != "unverified" {
+		t.Errorf("expected unverified, got %+v %v", result, err)
+	}
+}
+
+func TestRepairVersion_FromCache(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "app.tar.gz")
+	writeTestTarGz(t, archive, []testArchiveEntry{
+		{name: "app/bin/app", body: "#!/bin/sh\n", mode: 0755},
+	})
+	content, _ := os.ReadFile(archive)
+	srv, requests, _ := newTestArtifactServer(t, content)
+
+	if err := installPath(baseDir, srv.URL+"/app.tar.gz", "app", "1.0.0", installOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	binary := filepath.Join(baseDir, "app", "1.0.0", "bin", "app")
+	os.WriteFile(binary, []byte("tampered"), 0755)
+
+	result, err := verifyTarget(baseDir, "app", "1.0.0", true)
+	if err != nil || result.Status != "repaired" {
+		t.Fatalf("expected repaired, got %+v %v", result, err)
+	}
+	if data, _ := os.ReadFile(binary); string(data) != "#!/bin/sh\n" {
+		t.Errorf("expected restored contents, got %q", data)
+	}
+	if *requests != 1 {
+		t.Errorf("repair should use the cached artifact, got %d requests", *requests)
+	}
+	if meta, _ := readVersionMeta(baseDir, "app", "1.0.0"); meta == nil {
+		t.Error("repair should keep the install metadata")
+	}
+	if _, issues, _ := verifyVersion(baseDir, "app", "1.0.0"); len(issues) != 0 {
+		t.Errorf("expected a clean version after repair, got %v", issues)
+	}
+}
+
+func TestRepairVersion_SourceChanged(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	binary := filepath.Join(t.TempDir(), "tool")
+	os.WriteFile(binary, []byte("tool contents"), 0755)
+
+	if err := installPath(baseDir, binary, "tool", "1.0.0", installOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	os.Remove(filepath.Join(baseDir, "tool", "1.0.0", "bin", "tool"))
+	os.WriteFile(binary, []byte("newer contents"), 0755)
+
+	result, err := verifyTarget(baseDir, "tool", "1.0.0", true)
+	if err != nil || result.Status != "failed" || result.Error == "" {
+		t.Errorf("expected a failed repair, got %+v %v", result, err)
+	}
+
+	// With the original source back, the version can be restored
+	os.WriteFile(binary, []byte("tool contents"), 0755)
+	if err := repairVersion(baseDir, "tool", "1.0.0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if current, _ := getCurrentVersion(baseDir, "tool"); current != "1.0.0" {
+		t.Errorf("expected current=1.0.0, got %s", current)
+	}
+}
+
+func TestVerifyTargets(t *testing.T) {
+	setupTestEnv(t)
+	baseDir := t.TempDir()
+	installTestVersion(t, baseDir, "go", "1.22.0", "go")
+	installTestVersion(t, baseDir, "go", "1.23.4", "go")
+	installTestVersion(t, baseDir, "node", "22.1.0", "node")
+
+	names := func(targets []verifiedJSON) []string {
+		var out []string
+		for _, target := range targets {
+			out = append(out, target.App+"@"+target.Version)
+		}
+		return out
+	}
+
+	targets, err := verifyTargets(baseDir, nil)
+	if got := names(targets); err != nil || !slices.Equal(got, []string{"go@1.22.0", "go@1.23.4", "node@22.1.0"}) {
+		t.Errorf("unexpected targets: %v %v", got, err)
+	}
+	targets, err = verifyTargets(baseDir, []string{"go", "1.22"})
+	if got := names(targets); err != nil || !slices.Equal(got, []string{"go@1.22.0"}) {
+		t.Errorf("unexpected targets: %v %v", got, err)
+	}
+	if _, err := verifyTargets(baseDir, []string{"rust"}); err == nil {
+		t.Error("expected an error for an app that is not installed")
+	}
+}
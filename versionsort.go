@@ -0,0 +1,190 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parsedVersion is a version string split into its numeric release
+// components and pre-release identifiers, e.g. "1.22rc1" is release [1 22]
+// with pre-release ["rc1"], and "4.6.0-beta.2+build5" is [4 6 0] with
+// ["beta" "2"] (build metadata is ignored for ordering).
+type parsedVersion struct {
+	release []int
+	pre     []string
+}
+
+// parseVersion parses semver-like versions, including a leading "v",
+// any number of release components, "-" pre-releases, "+" build metadata
+// and Go's "1.22rc1" style. It reports false for anything else.
+func parseVersion(s string) (parsedVersion, bool) {
+	var v parsedVersion
+
+	if len(s) > 1 && (s[0] == 'v' || s[0] == 'V') && isDigit(s[1]) {
+		s = s[1:]
+	}
+	s, _, _ = strings.Cut(s, "+")
+
+	for {
+		end := 0
+		for end < len(s) && isDigit(s[end]) {
+			end++
+		}
+		if end == 0 {
+			return v, false
+		}
+		n, err := strconv.Atoi(s[:end])
+		if err != nil {
+			return v, false
+		}
+		v.release = append(v.release, n)
+		s = s[end:]
+
+		if !strings.HasPrefix(s, ".") {
+			break
+		}
+		s = s[1:]
+	}
+
+	switch {
+	case s == "":
+		return v, true
+	case s[0] == '-':
+		s = s[1:]
+	case !isLetter(s[0]):
+		return v, false
+	}
+	if s == "" {
+		return v, false
+	}
+
+	v.pre = strings.Split(s, ".")
+	return v, true
+}
+
+// compareVersions orders two version strings, returning -1, 0 or 1.
+// Versions that parse are ordered by semver precedence, so 1.9.0 < 1.10.0
+// and 4.6.0-beta2 < 4.6.0; anything else, and ties such as 1.23 vs 1.23.0,
+// fall back to natural order.
+func compareVersions(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if okA && okB {
+		if c := compareParsedVersions(va, vb); c != 0 {
+			return c
+		}
+	}
+	return naturalCompare(a, b)
+}
+
+func compareParsedVersions(a, b parsedVersion) int {
+	for i := 0; i < len(a.release) || i < len(b.release); i++ {
+		var x, y int
+		if i < len(a.release) {
+			x = a.release[i]
+		}
+		if i < len(b.release) {
+			y = b.release[i]
+		}
+		if x != y {
+			return compareInts(x, y)
+		}
+	}
+
+	// A pre-release sorts before the release itself
+	switch {
+	case len(a.pre) == 0 && len(b.pre) == 0:
+		return 0
+	case len(a.pre) == 0:
+		return 1
+	case len(b.pre) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a.pre) && i < len(b.pre); i++ {
+		if c := comparePreRelease(a.pre[i], b.pre[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(a.pre), len(b.pre))
+}
+
+// comparePreRelease compares pre-release identifiers: numeric identifiers
+// numerically and before alphanumeric ones, which use natural order so that
+// beta2 < beta10.
+func comparePreRelease(a, b string) int {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInts(x, y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return naturalCompare(a, b)
+}
+
+// naturalCompare compares strings treating runs of digits as numbers, so
+// "build9" < "build10".
+func naturalCompare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			si, sj := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			x := strings.TrimLeft(a[si:i], "0")
+			y := strings.TrimLeft(b[sj:j], "0")
+			if len(x) != len(y) {
+				return compareInts(len(x), len(y))
+			}
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+			continue
+		}
+
+		if a[i] != b[j] {
+			return compareInts(int(a[i]), int(b[j]))
+		}
+		i++
+		j++
+	}
+
+	if c := compareInts(len(a)-i, len(b)-j); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// sortVersions sorts versions from oldest to newest.
+func sortVersions(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+}
+
+func compareInts(x, y int) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
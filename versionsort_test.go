@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSortVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		expected []string
+	}{
+		{
+			name:     "numeric components",
+			versions: []string{"1.10.0", "1.9.0", "1.2.0", "10.0.0"},
+			expected: []string{"1.2.0", "1.9.0", "1.10.0", "10.0.0"},
+		},
+		{
+			name:     "semver pre-releases",
+			versions: []string{"4.6.0", "4.6.0-beta10", "4.6.0-rc1", "4.6.0-beta2", "4.5.1"},
+			expected: []string{"4.5.1", "4.6.0-beta2", "4.6.0-beta10", "4.6.0-rc1", "4.6.0"},
+		},
+		{
+			name:     "semver pre-release identifiers",
+			versions: []string{"1.0.0-alpha.beta", "1.0.0-alpha.1", "1.0.0-alpha", "1.0.0-beta.11", "1.0.0-beta.2", "1.0.0-beta", "1.0.0-rc.1", "1.0.0"},
+			expected: []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0"},
+		},
+		{
+			name:     "go style",
+			versions: []string{"1.22.0", "1.22rc1", "1.21.5", "1.22beta1", "1.22.1"},
+			expected: []string{"1.21.5", "1.22beta1", "1.22rc1", "1.22.0", "1.22.1"},
+		},
+		{
+			name:     "v prefix and build metadata",
+			versions: []string{"v1.2.0+build5", "v1.10.0", "v1.2.0-rc1"},
+			expected: []string{"v1.2.0-rc1", "v1.2.0+build5", "v1.10.0"},
+		},
+		{
+			name:     "natural order fallback",
+			versions: []string{"nightly-10", "nightly-9", "nightly-100"},
+			expected: []string{"nightly-9", "nightly-10", "nightly-100"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions := append([]string{}, tt.versions...)
+			sortVersions(versions)
+			if strings.Join(versions, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("expected %v, got %v", tt.expected, versions)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		ok      bool
+		release int
+		pre     string
+	}{
+		{"1.23.0", true, 3, ""},
+		{"v2", true, 1, ""},
+		{"1.22rc1", true, 2, "rc1"},
+		{"4.6.0-beta.2+build", true, 3, "beta.2"},
+		{"nightly", false, 0, ""},
+		{"1.2_3", false, 0, ""},
+		{"1.2-", false, 0, ""},
+	}
+
+	for _, tt := range tests {
+		v, ok := parseVersion(tt.input)
+		if ok != tt.ok {
+			t.Errorf("parseVersion(%q) ok = %v, want %v", tt.input, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if len(v.release) != tt.release || strings.Join(v.pre, ".") != tt.pre {
+			t.Errorf("parseVersion(%q) = %+v", tt.input, v)
+		}
+	}
+}
+
+func TestCompareVersions_Equal(t *testing.T) {
+	if compareVersions("1.2.3", "1.2.3") != 0 {
+		t.Error("identical versions should compare equal")
+	}
+	// Equivalent versions still have a stable order
+	if compareVersions("1.23", "1.23.0") == 0 {
+		t.Error("distinct strings should not compare equal")
+	}
+}